@@ -0,0 +1,190 @@
+package lox
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Channel is a buffered or unbuffered channel between tasks, created by the
+// chan() native. Its state is guarded by the lock of the owning scheduler.
+type Channel struct {
+	id       int
+	sched    *scheduler
+	capacity int
+	buffer   []interface{}
+	closed   bool
+	recvq    []*pending
+	sendq    []*pending
+}
+
+// NewChannel ...
+func NewChannel(s *scheduler, capacity int) *Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels++
+	return &Channel{id: s.channels, sched: s, capacity: capacity}
+}
+
+// trySend hands value to a parked receiver or buffers it. The caller must
+// hold the scheduler lock.
+func (ch *Channel) trySend(value interface{}) bool {
+	if p := ch.popLive(&ch.recvq); p != nil {
+		ch.sched.complete(p, value, true)
+		return true
+	}
+	if len(ch.buffer) < ch.capacity {
+		ch.buffer = append(ch.buffer, value)
+		return true
+	}
+	return false
+}
+
+// tryRecv takes a value from the buffer or a parked sender. ok is false once
+// the channel is closed and drained; ready is false if the receive would
+// block. The caller must hold the scheduler lock.
+func (ch *Channel) tryRecv() (value interface{}, ok bool, ready bool) {
+	if len(ch.buffer) > 0 {
+		value = ch.buffer[0]
+		ch.buffer = ch.buffer[1:]
+		if p := ch.popLive(&ch.sendq); p != nil {
+			ch.buffer = append(ch.buffer, p.value)
+			ch.sched.complete(p, nil, true)
+		}
+		return value, true, true
+	}
+	if p := ch.popLive(&ch.sendq); p != nil {
+		ch.sched.complete(p, nil, true)
+		return p.value, true, true
+	}
+	if ch.closed {
+		return nil, false, true
+	}
+	return nil, false, false
+}
+
+// Close wakes every parked receiver with nil and fails every parked sender.
+func (ch *Channel) Close() {
+	ch.sched.mu.Lock()
+	defer ch.sched.mu.Unlock()
+	if ch.closed {
+		panic(&RuntimeError{Msg: "Close of closed channel."})
+	}
+	ch.closed = true
+	for p := ch.popLive(&ch.recvq); p != nil; p = ch.popLive(&ch.recvq) {
+		ch.sched.complete(p, nil, false)
+	}
+	for p := ch.popLive(&ch.sendq); p != nil; p = ch.popLive(&ch.sendq) {
+		p.w.closed = true
+		ch.sched.complete(p, nil, false)
+	}
+}
+
+// popLive removes entries from queue until it finds one whose waiter has not
+// been completed through another channel yet.
+func (ch *Channel) popLive(queue *[]*pending) *pending {
+	for len(*queue) > 0 {
+		p := (*queue)[0]
+		*queue = (*queue)[1:]
+		if !p.w.done {
+			return p
+		}
+	}
+	return nil
+}
+
+// dequeue drops every entry of w from both queues.
+func (ch *Channel) dequeue(w *waiter) {
+	ch.recvq = removeWaiter(ch.recvq, w)
+	ch.sendq = removeWaiter(ch.sendq, w)
+}
+
+func removeWaiter(queue []*pending, w *waiter) []*pending {
+	kept := queue[:0]
+	for _, p := range queue {
+		if p.w != w {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// String ...
+func (ch *Channel) String() string {
+	return fmt.Sprintf("<chan %d>", ch.id)
+}
+
+// Chan ...
+type Chan struct{}
+
+// Arity ...
+func (c Chan) Arity() int {
+	return 1
+}
+
+// Call ...
+func (c Chan) Call(i *Interpreter, args []interface{}) interface{} {
+	capacity, ok := args[0].(float64)
+	if !ok || capacity < 0 || capacity != float64(int(capacity)) {
+		panic(&RuntimeError{Msg: "Channel capacity must be a non-negative integer."})
+	}
+	return NewChannel(i.tasks, int(capacity))
+}
+
+// Send ...
+type Send struct{}
+
+// Arity ...
+func (s Send) Arity() int {
+	return 2
+}
+
+// Call ...
+func (s Send) Call(i *Interpreter, args []interface{}) interface{} {
+	ch := toChannel("send", args[0])
+	i.tasks.choose(i.task, []chanOp{{ch: ch, send: true, value: args[1]}}, true, "send on "+ch.String())
+	return nil
+}
+
+// Recv ...
+type Recv struct{}
+
+// Arity ...
+func (r Recv) Arity() int {
+	return 1
+}
+
+// Call ...
+func (r Recv) Call(i *Interpreter, args []interface{}) interface{} {
+	ch := toChannel("recv", args[0])
+	_, value, _ := i.tasks.choose(i.task, []chanOp{{ch: ch}}, true, "recv on "+ch.String())
+	return value
+}
+
+// Close ...
+type Close struct{}
+
+// Arity ...
+func (c Close) Arity() int {
+	return 1
+}
+
+// Call ...
+func (c Close) Call(i *Interpreter, args []interface{}) interface{} {
+	switch v := args[0].(type) {
+	case *Channel:
+		v.Close()
+	case *Generator:
+		v.Close()
+	default:
+		panic(&RuntimeError{Msg: fmt.Sprintf("Can only close channels and generators, not %v.", reflect.TypeOf(args[0]))})
+	}
+	return nil
+}
+
+func toChannel(native string, value interface{}) *Channel {
+	ch, ok := value.(*Channel)
+	if !ok {
+		panic(&RuntimeError{Msg: fmt.Sprintf("%s() expects a channel.", native)})
+	}
+	return ch
+}
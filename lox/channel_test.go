@@ -0,0 +1,87 @@
+package lox
+
+import (
+	"testing"
+)
+
+// channelsProgram squares numbers in a spawned task.
+const channelsProgram = `
+fun square(jobs, results) {
+  var j = recv(jobs);
+  while (j) {
+    send(results, j * j);
+    j = recv(jobs);
+  }
+  close(results);
+}
+var jobs = chan(3);
+var results = chan(0);
+spawn square(jobs, results);
+send(jobs, 1);
+send(jobs, 2);
+send(jobs, 3);
+close(jobs);
+var total = 0;
+var r = recv(results);
+while (r) {
+  total = total + r;
+  r = recv(results);
+}
+`
+
+// TestChannels ...
+func TestChannels(t *testing.T) {
+	i := interpret(channelsProgram)
+	if got := i.GlobalEnv.Get("total"); got != 14.0 {
+		t.Errorf("got %v, expected 14", got)
+	}
+}
+
+// selectProgram receives from whichever channel is ready.
+const selectProgram = `
+var a = chan(0);
+var b = chan(1);
+var got = "none";
+send(b, "b");
+select {
+  case var v = recv(a) { got = v; }
+  case var v = recv(b) { got = v; }
+}
+var fallback = "none";
+select {
+  case recv(a) { fallback = "a"; }
+  default { fallback = "default"; }
+}
+`
+
+// TestSelect ...
+func TestSelect(t *testing.T) {
+	i := interpret(selectProgram)
+	if got := i.GlobalEnv.Get("got"); got != "b" {
+		t.Errorf("got %v, expected b", got)
+	}
+	if got := i.GlobalEnv.Get("fallback"); got != "default" {
+		t.Errorf("got %v, expected default", got)
+	}
+}
+
+// TestDeadlock ...
+func TestDeadlock(t *testing.T) {
+	i := interpret(`
+fun wait(c) { recv(c); }
+var reached = false;
+spawn wait(chan(0));
+recv(chan(0));
+reached = true;
+`)
+	if got := i.GlobalEnv.Get("reached"); got != false {
+		t.Error("expected the deadlock to abort the main task")
+	}
+	i.tasks.mu.Lock()
+	defer i.tasks.mu.Unlock()
+	for _, task := range i.tasks.tasks {
+		if task.waiter != nil {
+			t.Errorf("task %d is still blocked after the deadlock", task.id)
+		}
+	}
+}
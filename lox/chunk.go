@@ -0,0 +1,200 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+)
+
+// OpCode is a single bytecode instruction of the VM.
+type OpCode byte
+
+// const ...
+const (
+	OpConstant OpCode = iota
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+	OpGetLocal
+	OpSetLocal
+	OpGetGlobal
+	OpDefineGlobal
+	OpSetGlobal
+	OpGetUpvalue
+	OpSetUpvalue
+	OpGetProperty
+	OpSetProperty
+	OpEqual
+	OpNotEqual
+	OpGreater
+	OpGreaterEqual
+	OpLess
+	OpLessEqual
+	OpAdd
+	OpSubtract
+	OpMultiply
+	OpDivide
+	OpRange
+	OpRangeInclusive
+	OpNot
+	OpNegate
+	OpPrint
+	OpJump
+	OpJumpIfFalse
+	OpLoop
+	OpCall
+	OpClosure
+	OpCloseUpvalue
+	OpReturn
+)
+
+var opNames = [...]string{
+	OpConstant:       "OP_CONSTANT",
+	OpNil:            "OP_NIL",
+	OpTrue:           "OP_TRUE",
+	OpFalse:          "OP_FALSE",
+	OpPop:            "OP_POP",
+	OpGetLocal:       "OP_GET_LOCAL",
+	OpSetLocal:       "OP_SET_LOCAL",
+	OpGetGlobal:      "OP_GET_GLOBAL",
+	OpDefineGlobal:   "OP_DEFINE_GLOBAL",
+	OpSetGlobal:      "OP_SET_GLOBAL",
+	OpGetUpvalue:     "OP_GET_UPVALUE",
+	OpSetUpvalue:     "OP_SET_UPVALUE",
+	OpGetProperty:    "OP_GET_PROPERTY",
+	OpSetProperty:    "OP_SET_PROPERTY",
+	OpEqual:          "OP_EQUAL",
+	OpNotEqual:       "OP_NOT_EQUAL",
+	OpGreater:        "OP_GREATER",
+	OpGreaterEqual:   "OP_GREATER_EQUAL",
+	OpLess:           "OP_LESS",
+	OpLessEqual:      "OP_LESS_EQUAL",
+	OpAdd:            "OP_ADD",
+	OpSubtract:       "OP_SUBTRACT",
+	OpMultiply:       "OP_MULTIPLY",
+	OpDivide:         "OP_DIVIDE",
+	OpRange:          "OP_RANGE",
+	OpRangeInclusive: "OP_RANGE_INCLUSIVE",
+	OpNot:            "OP_NOT",
+	OpNegate:         "OP_NEGATE",
+	OpPrint:          "OP_PRINT",
+	OpJump:           "OP_JUMP",
+	OpJumpIfFalse:    "OP_JUMP_IF_FALSE",
+	OpLoop:           "OP_LOOP",
+	OpCall:           "OP_CALL",
+	OpClosure:        "OP_CLOSURE",
+	OpCloseUpvalue:   "OP_CLOSE_UPVALUE",
+	OpReturn:         "OP_RETURN",
+}
+
+// String ...
+func (op OpCode) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return fmt.Sprintf("OpCode(%d)", op)
+}
+
+// Chunk is the bytecode of one function, with the source line and span of
+// every byte and the constants it refers to.
+type Chunk struct {
+	Code      []byte
+	Lines     []int
+	Spans     []Span
+	Constants []interface{}
+}
+
+func (c *Chunk) write(b byte, span Span) {
+	c.Code = append(c.Code, b)
+	c.Lines = append(c.Lines, span.Line)
+	c.Spans = append(c.Spans, span)
+}
+
+func (c *Chunk) addConstant(value interface{}) int {
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
+
+func (c *Chunk) readShort(offset int) int {
+	return int(c.Code[offset])<<8 | int(c.Code[offset+1])
+}
+
+// CompiledFunction is a function compiled to bytecode, before it is bound
+// to its upvalues by OpClosure.
+type CompiledFunction struct {
+	Name         string
+	Arity        int
+	UpvalueCount int
+	Chunk        Chunk
+}
+
+// String ...
+func (f *CompiledFunction) String() string {
+	if f.Name == "" {
+		return "<script>"
+	}
+	return fmt.Sprintf("<fn %s>", f.Name)
+}
+
+// Disassemble writes a listing of fn and of every function nested in it.
+func Disassemble(w io.Writer, fn *CompiledFunction) {
+	name := fn.Name
+	if name == "" {
+		name = "script"
+	}
+	fmt.Fprintf(w, "== %s ==\n", name)
+	for offset := 0; offset < len(fn.Chunk.Code); {
+		offset = disassembleInstruction(w, &fn.Chunk, offset)
+	}
+	for _, constant := range fn.Chunk.Constants {
+		if nested, ok := constant.(*CompiledFunction); ok {
+			fmt.Fprintln(w)
+			Disassemble(w, nested)
+		}
+	}
+}
+
+func disassembleInstruction(w io.Writer, c *Chunk, offset int) int {
+	fmt.Fprintf(w, "%04d ", offset)
+	if offset > 0 && c.Lines[offset] == c.Lines[offset-1] {
+		fmt.Fprint(w, "   | ")
+	} else {
+		fmt.Fprintf(w, "%4d ", c.Lines[offset])
+	}
+
+	op := OpCode(c.Code[offset])
+	switch op {
+	case OpConstant, OpGetGlobal, OpDefineGlobal, OpSetGlobal, OpGetProperty, OpSetProperty:
+		idx := c.readShort(offset + 1)
+		fmt.Fprintf(w, "%-18s %4d '%v'\n", op, idx, c.Constants[idx])
+		return offset + 3
+	case OpGetLocal, OpSetLocal, OpGetUpvalue, OpSetUpvalue, OpCall:
+		fmt.Fprintf(w, "%-18s %4d\n", op, c.Code[offset+1])
+		return offset + 2
+	case OpJump, OpJumpIfFalse:
+		jump := c.readShort(offset + 1)
+		fmt.Fprintf(w, "%-18s %4d -> %d\n", op, offset, offset+3+jump)
+		return offset + 3
+	case OpLoop:
+		jump := c.readShort(offset + 1)
+		fmt.Fprintf(w, "%-18s %4d -> %d\n", op, offset, offset+3-jump)
+		return offset + 3
+	case OpClosure:
+		idx := c.readShort(offset + 1)
+		fn := c.Constants[idx].(*CompiledFunction)
+		fmt.Fprintf(w, "%-18s %4d %v\n", op, idx, fn)
+		offset += 3
+		for j := 0; j < fn.UpvalueCount; j++ {
+			kind := "upvalue"
+			if c.Code[offset] == 1 {
+				kind = "local"
+			}
+			fmt.Fprintf(w, "%04d    |                      %s %d\n", offset, kind, c.Code[offset+1])
+			offset += 2
+		}
+		return offset
+	default:
+		fmt.Fprintf(w, "%s\n", op)
+		return offset + 1
+	}
+}
@@ -0,0 +1,740 @@
+package lox
+
+import (
+	"fmt"
+)
+
+// ClosureCompiler converts statements once into Go closures, so running
+// them neither dispatches through Accept nor switches on operator types.
+// Local variables are resolved to slots of a per-call frame at compile
+// time; only globals are still looked up by name.
+type ClosureCompiler struct {
+	enclosing  *ClosureCompiler
+	proto      *closureProto
+	isScript   bool
+	locals     []*closureLocal
+	scopeDepth int
+	errors     []error
+}
+
+// closureLocal is a local variable of the function being compiled. A local
+// captured by a nested function lives in a cell, so the closure keeps it
+// alive after the frame is gone.
+type closureLocal struct {
+	name     string
+	depth    int
+	slot     int
+	captured bool
+}
+
+// closureProto is a compiled function, before it is bound to its upvalues.
+type closureProto struct {
+	name     string
+	params   []*closureLocal
+	slots    int
+	upvalues []upvalueRef
+	body     execFn
+}
+
+type cell struct {
+	value interface{}
+}
+
+type closureFrame struct {
+	i        *Interpreter
+	slots    []interface{}
+	upvalues []*cell
+	// depth is the number of calls in progress, including this one.
+	depth int
+	// proto is the function running in the frame, nil for the script. at
+	// is the call it is making, once it makes one, and caller the frame
+	// that called it. callee is the frame of the compiled function being
+	// called; it is left set when a runtime error unwinds the call, so the
+	// frame that raised it can be found.
+	proto  *closureProto
+	at     *Span
+	caller *closureFrame
+	callee *closureFrame
+}
+
+// trace returns the traceback of the calls in progress, innermost first,
+// for execution having reached span in f.
+func (f *closureFrame) trace(span Span) []Frame {
+	trace := make([]Frame, 0)
+	for ; f != nil; f = f.caller {
+		name := "script"
+		if f.proto != nil {
+			name = f.proto.name
+		}
+		trace = append(trace, Frame{Function: name, Span: span})
+		if f.caller != nil {
+			span = *f.caller.at
+		}
+	}
+	return trace
+}
+
+// stacktrace is the traceback while f calls a native.
+func (f *closureFrame) stacktrace() []Frame {
+	return f.trace(*f.at)
+}
+
+// traced records the calls in progress on r, if it is a runtime error
+// raised inside a function called from f that has no traceback yet.
+func (f *closureFrame) traced(r interface{}) {
+	for f.callee != nil {
+		f = f.callee
+	}
+	re, ok := r.(*RuntimeError)
+	if !ok || re.Trace != nil || f.caller == nil {
+		return
+	}
+	span := Span{Line: re.Line}
+	if re.Token != nil {
+		span = re.Token.Span()
+	}
+	re.Trace = f.trace(span)
+}
+
+// evalFn evaluates a compiled expression.
+type evalFn func(f *closureFrame) interface{}
+
+// execFn runs a compiled statement. returned reports that a return
+// statement ran, with value being its result.
+type execFn func(f *closureFrame) (value interface{}, returned bool)
+
+// ClosureProgram is a script compiled by the ClosureCompiler.
+type ClosureProgram struct {
+	proto *closureProto
+}
+
+// CompileClosures compiles a program to Go closures. As for the VM,
+// generator and async functions, await, spawn and select are out of scope
+// for the closure compiler, and CompileClosures reports them as errors.
+func CompileClosures(statements []Stmt) (*ClosureProgram, []error) {
+	c := &ClosureCompiler{proto: &closureProto{}, isScript: true}
+	c.proto.body = c.block(statements)
+	return &ClosureProgram{proto: c.proto}, c.errors
+}
+
+// Run executes the program, reporting runtime errors like
+// Interpreter.Interpret does.
+func (p *ClosureProgram) Run(i *Interpreter) Diagnostics {
+	i.reporter.start(false)
+	p.run(i)
+	return i.reporter.stop().Diagnostics()
+}
+
+func (p *ClosureProgram) run(i *Interpreter) {
+	i.begin(i.limits.ctx)
+	i.tasks.enter(i.task)
+	defer i.tasks.leave(i.task)
+	script := &closureFrame{i: i, slots: make([]interface{}, p.proto.slots), depth: i.depth}
+	defer func() {
+		if r := recover(); r != nil {
+			script.traced(r)
+			switch e := r.(type) {
+			case *RuntimeError:
+				i.reporter.report(e)
+			case *DeadlockError:
+				i.reporter.report(e)
+			case *LimitError:
+				i.reporter.report(e)
+			default:
+				panic(r)
+			}
+		}
+	}()
+	p.proto.body(script)
+	i.loop.Run()
+}
+
+// closureFunction is a compiled function bound to the cells it captures.
+type closureFunction struct {
+	proto    *closureProto
+	upvalues []*cell
+}
+
+// Arity ...
+func (cf *closureFunction) Arity() int {
+	return len(cf.proto.params)
+}
+
+// Call ...
+func (cf *closureFunction) Call(i *Interpreter, args []interface{}) interface{} {
+	return cf.call(i, args, i.depth+1, nil)
+}
+
+// call runs the function in a new frame, called by caller, or by a native
+// if caller is nil.
+func (cf *closureFunction) call(i *Interpreter, args []interface{}, depth int, caller *closureFrame) interface{} {
+	f := &closureFrame{i: i, slots: make([]interface{}, cf.proto.slots), upvalues: cf.upvalues, depth: depth, proto: cf.proto, caller: caller}
+	if caller != nil {
+		caller.callee = f
+	}
+	for idx, param := range cf.proto.params {
+		if param.captured {
+			f.slots[param.slot] = &cell{args[idx]}
+		} else {
+			f.slots[param.slot] = args[idx]
+		}
+	}
+	value, _ := cf.proto.body(f)
+	if caller != nil {
+		caller.callee = nil
+	}
+	return value
+}
+
+// String ...
+func (cf *closureFunction) String() string {
+	return fmt.Sprintf("<fn %s>", cf.proto.name)
+}
+
+func (c *ClosureCompiler) statement(stmt Stmt) execFn {
+	return stmt.Accept(c).(execFn)
+}
+
+func (c *ClosureCompiler) expression(expr Expr) evalFn {
+	return expr.Accept(c).(evalFn)
+}
+
+func (c *ClosureCompiler) block(statements []Stmt) execFn {
+	compiled := make([]execFn, 0, len(statements))
+	for _, statement := range statements {
+		if statement != nil {
+			compiled = append(compiled, c.statement(statement))
+		}
+	}
+	return func(f *closureFrame) (interface{}, bool) {
+		for _, statement := range compiled {
+			f.i.step(0)
+			if value, returned := statement(f); returned {
+				return value, true
+			}
+		}
+		return nil, false
+	}
+}
+
+func (c *ClosureCompiler) errorAt(line int, message string) {
+	c.errors = append(c.errors, &CompileError{Line: line, Msg: message})
+}
+
+func (c *ClosureCompiler) unsupported(line int, feature string) execFn {
+	c.errorAt(line, fmt.Sprintf("%s is not supported by the closure compiler.", feature))
+	return func(f *closureFrame) (interface{}, bool) { return nil, false }
+}
+
+func (c *ClosureCompiler) addLocal(name string) *closureLocal {
+	l := &closureLocal{name: name, depth: c.scopeDepth, slot: len(c.locals)}
+	c.locals = append(c.locals, l)
+	if len(c.locals) > c.proto.slots {
+		c.proto.slots = len(c.locals)
+	}
+	return l
+}
+
+func (c *ClosureCompiler) resolveLocal(name string) *closureLocal {
+	for idx := len(c.locals) - 1; idx >= 0; idx-- {
+		if c.locals[idx].name == name {
+			return c.locals[idx]
+		}
+	}
+	return nil
+}
+
+func (c *ClosureCompiler) resolveUpvalue(name string) int {
+	if c.enclosing == nil {
+		return -1
+	}
+	if l := c.enclosing.resolveLocal(name); l != nil {
+		l.captured = true
+		return c.addUpvalue(l.slot, true)
+	}
+	if idx := c.enclosing.resolveUpvalue(name); idx != -1 {
+		return c.addUpvalue(idx, false)
+	}
+	return -1
+}
+
+func (c *ClosureCompiler) addUpvalue(index int, isLocal bool) int {
+	for idx, upvalue := range c.proto.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return idx
+		}
+	}
+	c.proto.upvalues = append(c.proto.upvalues, upvalueRef{index: index, isLocal: isLocal})
+	return len(c.proto.upvalues) - 1
+}
+
+// define binds a value to a new variable: a global at the top level, and a
+// local slot inside a scope. Whether the local needs a cell is only known
+// once the whole function is compiled, so it is checked when the
+// definition runs.
+func (c *ClosureCompiler) define(name string, value evalFn) execFn {
+	if c.scopeDepth == 0 {
+		return func(f *closureFrame) (interface{}, bool) {
+			f.i.GlobalEnv.Define(name, value(f))
+			return nil, false
+		}
+	}
+	l := c.addLocal(name)
+	return func(f *closureFrame) (interface{}, bool) {
+		if l.captured {
+			f.slots[l.slot] = &cell{value(f)}
+		} else {
+			f.slots[l.slot] = value(f)
+		}
+		return nil, false
+	}
+}
+
+// VisitExpressionStmt ...
+func (c *ClosureCompiler) VisitExpressionStmt(stmt *ExpressionStmt) interface{} {
+	expr := c.expression(stmt.Expression)
+	return execFn(func(f *closureFrame) (interface{}, bool) {
+		expr(f)
+		return nil, false
+	})
+}
+
+// VisitPrintStmt ...
+func (c *ClosureCompiler) VisitPrintStmt(stmt *PrintStmt) interface{} {
+	expr := c.expression(stmt.Expression)
+	span := stmt.Span()
+	return execFn(func(f *closureFrame) (interface{}, bool) {
+		fmt.Fprintln(f.i.stdout, f.i.stringify(expr(f), span))
+		return nil, false
+	})
+}
+
+// VisitVarStmt compiles the initializer before declaring the variable, so
+// the initializer sees any outer variable of the same name.
+func (c *ClosureCompiler) VisitVarStmt(stmt *VarStmt) interface{} {
+	value := evalFn(func(f *closureFrame) interface{} { return nil })
+	if stmt.Initializer != nil {
+		value = c.expression(stmt.Initializer)
+	}
+	return c.define(stmt.Name.Lexeme, value)
+}
+
+// VisitBlockStmt ...
+func (c *ClosureCompiler) VisitBlockStmt(stmt *BlockStmt) interface{} {
+	c.scopeDepth++
+	body := c.block(stmt.Statements)
+	c.scopeDepth--
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+	return body
+}
+
+// VisitIfStmt ...
+func (c *ClosureCompiler) VisitIfStmt(stmt *IfStmt) interface{} {
+	condition := c.expression(stmt.Condition)
+	thenBranch := c.statement(stmt.ThenBranch)
+	if stmt.ElseBranch == nil {
+		return execFn(func(f *closureFrame) (interface{}, bool) {
+			if f.i.isTruthy(condition(f)) {
+				return thenBranch(f)
+			}
+			return nil, false
+		})
+	}
+	elseBranch := c.statement(stmt.ElseBranch)
+	return execFn(func(f *closureFrame) (interface{}, bool) {
+		if f.i.isTruthy(condition(f)) {
+			return thenBranch(f)
+		}
+		return elseBranch(f)
+	})
+}
+
+// VisitWhileStmt ...
+func (c *ClosureCompiler) VisitWhileStmt(stmt *WhileStmt) interface{} {
+	condition := c.expression(stmt.Condition)
+	body := c.statement(stmt.Body)
+	return execFn(func(f *closureFrame) (interface{}, bool) {
+		for {
+			f.i.step(0)
+			if !f.i.isTruthy(condition(f)) {
+				break
+			}
+			if value, returned := body(f); returned {
+				return value, true
+			}
+		}
+		return nil, false
+	})
+}
+
+// VisitFunctionStmt ...
+func (c *ClosureCompiler) VisitFunctionStmt(stmt *FunctionStmt) interface{} {
+	if stmt.IsGenerator {
+		return c.unsupported(stmt.Name.Line, "Generator function")
+	}
+	if stmt.IsAsync {
+		return c.unsupported(stmt.Name.Line, "Async function")
+	}
+	// A local function is declared before its body is compiled so that it
+	// can refer to itself.
+	var l *closureLocal
+	if c.scopeDepth > 0 {
+		l = c.addLocal(stmt.Name.Lexeme)
+	}
+
+	fc := &ClosureCompiler{enclosing: c, proto: &closureProto{name: stmt.Name.Lexeme}, scopeDepth: 1}
+	for _, param := range stmt.Params {
+		fc.proto.params = append(fc.proto.params, fc.addLocal(param.Lexeme))
+	}
+	fc.proto.body = fc.block(stmt.Body)
+	c.errors = append(c.errors, fc.errors...)
+
+	proto := fc.proto
+	bind := func(f *closureFrame) *closureFunction {
+		upvalues := make([]*cell, len(proto.upvalues))
+		for idx, upvalue := range proto.upvalues {
+			if upvalue.isLocal {
+				upvalues[idx] = f.slots[upvalue.index].(*cell)
+			} else {
+				upvalues[idx] = f.upvalues[upvalue.index]
+			}
+		}
+		return &closureFunction{proto: proto, upvalues: upvalues}
+	}
+	if l == nil {
+		name := stmt.Name.Lexeme
+		return execFn(func(f *closureFrame) (interface{}, bool) {
+			f.i.GlobalEnv.Define(name, bind(f))
+			return nil, false
+		})
+	}
+	return execFn(func(f *closureFrame) (interface{}, bool) {
+		if l.captured {
+			self := &cell{}
+			f.slots[l.slot] = self
+			self.value = bind(f)
+		} else {
+			f.slots[l.slot] = bind(f)
+		}
+		return nil, false
+	})
+}
+
+// VisitReturnStmt ...
+func (c *ClosureCompiler) VisitReturnStmt(stmt *ReturnStmt) interface{} {
+	if c.isScript {
+		c.errorAt(stmt.Keyword.Line, "Can't return from top-level code.")
+		return execFn(func(f *closureFrame) (interface{}, bool) {
+			return nil, false
+		})
+	}
+	if stmt.Value == nil {
+		return execFn(func(f *closureFrame) (interface{}, bool) {
+			return nil, true
+		})
+	}
+	value := c.expression(stmt.Value)
+	return execFn(func(f *closureFrame) (interface{}, bool) {
+		return value(f), true
+	})
+}
+
+// VisitYieldStmt ...
+func (c *ClosureCompiler) VisitYieldStmt(stmt *YieldStmt) interface{} {
+	return c.unsupported(stmt.Keyword.Line, "yield")
+}
+
+// VisitSpawnStmt ...
+func (c *ClosureCompiler) VisitSpawnStmt(stmt *SpawnStmt) interface{} {
+	return c.unsupported(stmt.Keyword.Line, "spawn")
+}
+
+// VisitSelectStmt ...
+func (c *ClosureCompiler) VisitSelectStmt(stmt *SelectStmt) interface{} {
+	return c.unsupported(stmt.Keyword.Line, "select")
+}
+
+// VisitLiteralExpr ...
+func (c *ClosureCompiler) VisitLiteralExpr(expr *ExprLiteral) interface{} {
+	value := expr.Value
+	return evalFn(func(f *closureFrame) interface{} {
+		return value
+	})
+}
+
+// VisitGroupingExpr ...
+func (c *ClosureCompiler) VisitGroupingExpr(expr *ExprGrouping) interface{} {
+	return c.expression(expr.Expr)
+}
+
+// VisitVarExpr ...
+func (c *ClosureCompiler) VisitVarExpr(expr *ExprVar) interface{} {
+	name := expr.Name.Lexeme
+	if l := c.resolveLocal(name); l != nil {
+		return evalFn(func(f *closureFrame) interface{} {
+			if l.captured {
+				return f.slots[l.slot].(*cell).value
+			}
+			return f.slots[l.slot]
+		})
+	}
+	if idx := c.resolveUpvalue(name); idx != -1 {
+		return evalFn(func(f *closureFrame) interface{} {
+			return f.upvalues[idx].value
+		})
+	}
+	token := expr.Name
+	return evalFn(func(f *closureFrame) interface{} {
+		return f.i.getGlobal(token)
+	})
+}
+
+// VisitAssignExpr ...
+func (c *ClosureCompiler) VisitAssignExpr(expr *ExprAssign) interface{} {
+	value := c.expression(expr.Value)
+	name := expr.Name.Lexeme
+	if l := c.resolveLocal(name); l != nil {
+		return evalFn(func(f *closureFrame) interface{} {
+			v := value(f)
+			if l.captured {
+				f.slots[l.slot].(*cell).value = v
+			} else {
+				f.slots[l.slot] = v
+			}
+			return v
+		})
+	}
+	if idx := c.resolveUpvalue(name); idx != -1 {
+		return evalFn(func(f *closureFrame) interface{} {
+			v := value(f)
+			f.upvalues[idx].value = v
+			return v
+		})
+	}
+	return evalFn(func(f *closureFrame) interface{} {
+		v := value(f)
+		f.i.assignGlobal(expr.Name, v)
+		return v
+	})
+}
+
+// VisitUnaryExpr ...
+func (c *ClosureCompiler) VisitUnaryExpr(expr *ExprUnary) interface{} {
+	right := c.expression(expr.Right)
+	operator := expr.Operator
+	if operator.Type == TokenTypeMinus {
+		return evalFn(func(f *closureFrame) interface{} {
+			r := right(f)
+			if n, ok := r.(float64); ok {
+				return -n
+			}
+			return f.i.unaryOp(operator, r)
+		})
+	}
+	return evalFn(func(f *closureFrame) interface{} {
+		return f.i.unaryOp(operator, right(f))
+	})
+}
+
+// VisitBinaryExpr resolves the operator once, with a fast path for numbers
+// and strings and the shared binaryOp for everything else.
+func (c *ClosureCompiler) VisitBinaryExpr(expr *ExprBinary) interface{} {
+	left := c.expression(expr.Left)
+	right := c.expression(expr.Right)
+	operator := expr.Operator
+	slow := func(f *closureFrame, l interface{}, r interface{}) interface{} {
+		return f.i.binaryOp(operator, l, r)
+	}
+	switch operator.Type {
+	case TokenTypePlus:
+		return evalFn(func(f *closureFrame) interface{} {
+			l, r := left(f), right(f)
+			switch a := l.(type) {
+			case float64:
+				if b, ok := r.(float64); ok {
+					return a + b
+				}
+			case string:
+				if b, ok := r.(string); ok {
+					f.i.allocate(len(a)+len(b), operator.Line)
+					return a + b
+				}
+			}
+			return slow(f, l, r)
+		})
+	case TokenTypeMinus:
+		return evalFn(func(f *closureFrame) interface{} {
+			l, r := left(f), right(f)
+			if a, ok := l.(float64); ok {
+				if b, ok := r.(float64); ok {
+					return a - b
+				}
+			}
+			return slow(f, l, r)
+		})
+	case TokenTypeStar:
+		return evalFn(func(f *closureFrame) interface{} {
+			l, r := left(f), right(f)
+			if a, ok := l.(float64); ok {
+				if b, ok := r.(float64); ok {
+					return a * b
+				}
+			}
+			return slow(f, l, r)
+		})
+	case TokenTypeSlash:
+		return evalFn(func(f *closureFrame) interface{} {
+			l, r := left(f), right(f)
+			if a, ok := l.(float64); ok {
+				if b, ok := r.(float64); ok {
+					return a / b
+				}
+			}
+			return slow(f, l, r)
+		})
+	case TokenTypeLess:
+		return evalFn(func(f *closureFrame) interface{} {
+			l, r := left(f), right(f)
+			if a, ok := l.(float64); ok {
+				if b, ok := r.(float64); ok {
+					return a < b
+				}
+			}
+			return slow(f, l, r)
+		})
+	case TokenTypeLessEqual:
+		return evalFn(func(f *closureFrame) interface{} {
+			l, r := left(f), right(f)
+			if a, ok := l.(float64); ok {
+				if b, ok := r.(float64); ok {
+					return a <= b
+				}
+			}
+			return slow(f, l, r)
+		})
+	case TokenTypeGreater:
+		return evalFn(func(f *closureFrame) interface{} {
+			l, r := left(f), right(f)
+			if a, ok := l.(float64); ok {
+				if b, ok := r.(float64); ok {
+					return a > b
+				}
+			}
+			return slow(f, l, r)
+		})
+	case TokenTypeGreaterEqual:
+		return evalFn(func(f *closureFrame) interface{} {
+			l, r := left(f), right(f)
+			if a, ok := l.(float64); ok {
+				if b, ok := r.(float64); ok {
+					return a >= b
+				}
+			}
+			return slow(f, l, r)
+		})
+	}
+	return evalFn(func(f *closureFrame) interface{} {
+		return slow(f, left(f), right(f))
+	})
+}
+
+// VisitLogicalExpr ...
+func (c *ClosureCompiler) VisitLogicalExpr(expr *ExprLogical) interface{} {
+	left := c.expression(expr.Left)
+	right := c.expression(expr.Right)
+	if expr.Operator.Type == TokenTypeOr {
+		return evalFn(func(f *closureFrame) interface{} {
+			if l := left(f); f.i.isTruthy(l) {
+				return l
+			}
+			return right(f)
+		})
+	}
+	return evalFn(func(f *closureFrame) interface{} {
+		if l := left(f); !f.i.isTruthy(l) {
+			return l
+		}
+		return right(f)
+	})
+}
+
+// VisitCallExpr ...
+func (c *ClosureCompiler) VisitCallExpr(expr *ExprCall) interface{} {
+	callee := c.expression(expr.Callee)
+	arguments := make([]evalFn, len(expr.Arguments))
+	for idx, arg := range expr.Arguments {
+		arguments[idx] = c.expression(*arg)
+	}
+	paren := expr.Paren
+	span := paren.Span()
+	return evalFn(func(f *closureFrame) interface{} {
+		value := callee(f)
+		args := make([]interface{}, len(arguments))
+		for idx, arg := range arguments {
+			args[idx] = arg(f)
+		}
+		f.i.step(paren.Line)
+		f.at = &span
+		if cf, ok := value.(*closureFunction); ok && len(args) == len(cf.proto.params) {
+			f.i.enterCall(f.depth+1, paren.Line)
+			return cf.call(f.i, args, f.depth+1, f)
+		}
+		fn, ok := value.(Callable)
+		if !ok {
+			panic(runtimeErr(paren, "Can only call functions and classes."))
+		}
+		if !arityMatches(fn, len(args)) {
+			panic(runtimeErr(paren, fmt.Sprintf("Expected %d arguments but got %d.", fn.Arity(), len(args))))
+		}
+		f.i.enterCall(f.depth+1, paren.Line)
+		return callNative(f.i, fn, args, paren, f)
+	})
+}
+
+// VisitGetExpr ...
+func (c *ClosureCompiler) VisitGetExpr(expr *ExprGet) interface{} {
+	object := c.expression(expr.Object)
+	name := expr.Name
+	return evalFn(func(f *closureFrame) interface{} {
+		return getProperty(object(f), name)
+	})
+}
+
+// VisitSetExpr ...
+func (c *ClosureCompiler) VisitSetExpr(expr *ExprSet) interface{} {
+	object := c.expression(expr.Object)
+	value := c.expression(expr.Value)
+	name := expr.Name
+	return evalFn(func(f *closureFrame) interface{} {
+		o := object(f)
+		v := value(f)
+		setProperty(o, name, v)
+		return v
+	})
+}
+
+// callNative calls a Callable that is not compiled code for the engine t,
+// attributing the runtime errors it raises to the call at paren.
+func callNative(i *Interpreter, fn Callable, args []interface{}, paren Token, t tracer) interface{} {
+	previous := i.tracer
+	i.tracer = t
+	defer func() {
+		i.tracer = previous
+		if r := recover(); r != nil {
+			if re, ok := r.(*RuntimeError); ok && re.Line == 0 {
+				re.Line = paren.Line
+				re.Token = &paren
+			}
+			panic(r)
+		}
+	}()
+	return fn.Call(i, args)
+}
+
+// VisitAwaitExpr ...
+func (c *ClosureCompiler) VisitAwaitExpr(expr *ExprAwait) interface{} {
+	c.errorAt(expr.Keyword.Line, "await is not supported by the closure compiler.")
+	return evalFn(func(f *closureFrame) interface{} { return nil })
+}
@@ -0,0 +1,91 @@
+package lox
+
+import (
+	"testing"
+)
+
+func closureInterpret(source string) *Interpreter {
+	i := NewInterpreter()
+	program, errs := CompileClosures(mustParse(source))
+	if len(errs) > 0 {
+		panic(errs[0])
+	}
+	program.Run(i)
+	return i
+}
+
+// TestClosureCapture ...
+func TestClosureCapture(t *testing.T) {
+	i := closureInterpret(`
+var first;
+var second;
+for (var n = 1; n < 3; n = n + 1) {
+  var captured = n * 10;
+  fun get() { return captured; }
+  if (n == 1) first = get; else second = get;
+}
+fun outer() {
+  var x = "before";
+  fun set() { x = "after"; }
+  set();
+  return x;
+}
+var result = outer();
+`)
+	if got := i.GlobalEnv.Get("first").(Callable).Call(i, nil); got != 10.0 {
+		t.Errorf("got %v, expected 10", got)
+	}
+	if got := i.GlobalEnv.Get("second").(Callable).Call(i, nil); got != 20.0 {
+		t.Errorf("got %v, expected 20", got)
+	}
+	if got := i.GlobalEnv.Get("result"); got != "after" {
+		t.Errorf("got %v, expected after", got)
+	}
+}
+
+var benchmarkPrograms = map[string]string{
+	"Fib": `
+fun fib(n) { if (n < 2) return n; return fib(n - 1) + fib(n - 2); }
+fib(18);
+`,
+	"Loop": `
+{
+  var sum = 0;
+  for (var i = 0; i < 20000; i = i + 1) sum = sum + i;
+}
+`,
+	"Strings": `
+{
+  var s = "";
+  for (var i = 0; i < 2000; i = i + 1) s = s + "ab";
+}
+`,
+}
+
+func benchmarkInterpreter(b *testing.B, name string) {
+	stmts := mustParse(benchmarkPrograms[name])
+	i := NewInterpreter()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		i.Interpret(stmts)
+	}
+}
+
+func benchmarkClosures(b *testing.B, name string) {
+	program, errs := CompileClosures(mustParse(benchmarkPrograms[name]))
+	if len(errs) > 0 {
+		b.Fatal(errs)
+	}
+	i := NewInterpreter()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		program.Run(i)
+	}
+}
+
+func BenchmarkInterpreterFib(b *testing.B)     { benchmarkInterpreter(b, "Fib") }
+func BenchmarkClosuresFib(b *testing.B)        { benchmarkClosures(b, "Fib") }
+func BenchmarkInterpreterLoop(b *testing.B)    { benchmarkInterpreter(b, "Loop") }
+func BenchmarkClosuresLoop(b *testing.B)       { benchmarkClosures(b, "Loop") }
+func BenchmarkInterpreterStrings(b *testing.B) { benchmarkInterpreter(b, "Strings") }
+func BenchmarkClosuresStrings(b *testing.B)    { benchmarkClosures(b, "Strings") }
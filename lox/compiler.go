@@ -0,0 +1,502 @@
+package lox
+
+import (
+	"fmt"
+)
+
+// Compiler turns the statements produced by the parser into bytecode for
+// the VM. It walks the tree with the same visitor interfaces as the
+// Interpreter, with one Compiler per function being compiled.
+type Compiler struct {
+	enclosing  *Compiler
+	function   *CompiledFunction
+	isScript   bool
+	locals     []local
+	upvalues   []upvalueRef
+	scopeDepth int
+	// token is where the code being emitted comes from in the source.
+	token  Token
+	errors []error
+}
+
+type local struct {
+	name     string
+	depth    int
+	captured bool
+}
+
+type upvalueRef struct {
+	index   int
+	isLocal bool
+}
+
+// maxLocals bounds the slots of a frame, which are addressed by one byte.
+const maxLocals = 256
+
+// Compile compiles a program to the function the VM runs as its script.
+// Generator and async functions, await, spawn and select are out of scope
+// for the VM: they suspend calls, which only the Interpreter can do, and
+// Compile reports them as errors.
+func Compile(statements []Stmt) (*CompiledFunction, []error) {
+	c := newCompiler(nil, "", true)
+	for _, statement := range statements {
+		c.statement(statement)
+	}
+	c.emitReturn()
+	return c.function, c.errors
+}
+
+func newCompiler(enclosing *Compiler, name string, isScript bool) *Compiler {
+	c := &Compiler{enclosing: enclosing, isScript: isScript}
+	c.function = &CompiledFunction{Name: name}
+	// Slot zero holds the function being called.
+	c.locals = append(c.locals, local{name: "", depth: 0})
+	if enclosing != nil {
+		c.token = enclosing.token
+	}
+	return c
+}
+
+func (c *Compiler) statement(stmt Stmt) {
+	if stmt != nil {
+		stmt.Accept(c)
+	}
+}
+
+func (c *Compiler) expression(expr Expr) {
+	if expr != nil {
+		expr.Accept(c)
+	}
+}
+
+func (c *Compiler) errorAt(line int, message string) {
+	c.errors = append(c.errors, &CompileError{Line: line, Msg: message})
+}
+
+func (c *Compiler) chunk() *Chunk {
+	return &c.function.Chunk
+}
+
+func (c *Compiler) emit(bytes ...byte) {
+	for _, b := range bytes {
+		c.chunk().write(b, c.token.Span())
+	}
+}
+
+func (c *Compiler) emitOp(op OpCode, operands ...byte) {
+	c.emit(byte(op))
+	c.emit(operands...)
+}
+
+func (c *Compiler) emitShort(op OpCode, operand int) {
+	c.emit(byte(op), byte(operand>>8), byte(operand))
+}
+
+func (c *Compiler) emitConstant(value interface{}) {
+	c.emitShort(OpConstant, c.makeConstant(value))
+}
+
+func (c *Compiler) makeConstant(value interface{}) int {
+	idx := c.chunk().addConstant(value)
+	if idx > 0xffff {
+		c.errorAt(c.token.Line, "Too many constants in one chunk.")
+	}
+	return idx
+}
+
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emitShort(op, 0xffff)
+	return len(c.chunk().Code) - 2
+}
+
+func (c *Compiler) patchJump(offset int) {
+	jump := len(c.chunk().Code) - offset - 2
+	if jump > 0xffff {
+		c.errorAt(c.token.Line, "Too much code to jump over.")
+	}
+	c.chunk().Code[offset] = byte(jump >> 8)
+	c.chunk().Code[offset+1] = byte(jump)
+}
+
+func (c *Compiler) emitLoop(start int) {
+	jump := len(c.chunk().Code) - start + 3
+	if jump > 0xffff {
+		c.errorAt(c.token.Line, "Loop body too large.")
+	}
+	c.emitShort(OpLoop, jump)
+}
+
+func (c *Compiler) emitReturn() {
+	c.emitOp(OpNil)
+	c.emitOp(OpReturn)
+}
+
+func (c *Compiler) beginScope() {
+	c.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	c.scopeDepth--
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		if c.locals[len(c.locals)-1].captured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+func (c *Compiler) addLocal(name string) {
+	if len(c.locals) == maxLocals {
+		c.errorAt(c.token.Line, "Too many local variables in function.")
+		return
+	}
+	c.locals = append(c.locals, local{name: name, depth: c.scopeDepth})
+}
+
+func (c *Compiler) resolveLocal(name string) int {
+	for idx := len(c.locals) - 1; idx > 0; idx-- {
+		if c.locals[idx].name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) resolveUpvalue(name string) int {
+	if c.enclosing == nil {
+		return -1
+	}
+	if idx := c.enclosing.resolveLocal(name); idx != -1 {
+		c.enclosing.locals[idx].captured = true
+		return c.addUpvalue(idx, true)
+	}
+	if idx := c.enclosing.resolveUpvalue(name); idx != -1 {
+		return c.addUpvalue(idx, false)
+	}
+	return -1
+}
+
+func (c *Compiler) addUpvalue(index int, isLocal bool) int {
+	for idx, upvalue := range c.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return idx
+		}
+	}
+	if len(c.upvalues) == maxLocals {
+		c.errorAt(c.token.Line, "Too many closure variables in function.")
+		return 0
+	}
+	c.upvalues = append(c.upvalues, upvalueRef{index: index, isLocal: isLocal})
+	c.function.UpvalueCount = len(c.upvalues)
+	return len(c.upvalues) - 1
+}
+
+// defineVariable binds the value on top of the stack to name: as a global
+// at the top level, and as a new local slot inside a scope.
+func (c *Compiler) defineVariable(name string) {
+	if c.scopeDepth > 0 {
+		c.addLocal(name)
+		return
+	}
+	c.emitShort(OpDefineGlobal, c.makeConstant(name))
+}
+
+func (c *Compiler) namedVariable(name string, assign bool) {
+	getOp, setOp := OpGetGlobal, OpSetGlobal
+	arg := c.resolveLocal(name)
+	if arg != -1 {
+		getOp, setOp = OpGetLocal, OpSetLocal
+	} else if arg = c.resolveUpvalue(name); arg != -1 {
+		getOp, setOp = OpGetUpvalue, OpSetUpvalue
+	} else {
+		op := getOp
+		if assign {
+			op = setOp
+		}
+		c.emitShort(op, c.makeConstant(name))
+		return
+	}
+	if assign {
+		c.emitOp(setOp, byte(arg))
+	} else {
+		c.emitOp(getOp, byte(arg))
+	}
+}
+
+func (c *Compiler) unsupported(line int, feature string) {
+	c.errorAt(line, fmt.Sprintf("%s is not supported by the bytecode VM.", feature))
+}
+
+// VisitExpressionStmt ...
+func (c *Compiler) VisitExpressionStmt(stmt *ExpressionStmt) interface{} {
+	c.expression(stmt.Expression)
+	c.emitOp(OpPop)
+	return nil
+}
+
+// VisitPrintStmt ...
+func (c *Compiler) VisitPrintStmt(stmt *PrintStmt) interface{} {
+	c.expression(stmt.Expression)
+	c.emitOp(OpPrint)
+	return nil
+}
+
+// VisitVarStmt compiles the initializer before declaring the variable, so
+// the initializer sees any outer variable of the same name, as it does in
+// the Interpreter.
+func (c *Compiler) VisitVarStmt(stmt *VarStmt) interface{} {
+	c.token = stmt.Name
+	if stmt.Initializer != nil {
+		c.expression(stmt.Initializer)
+	} else {
+		c.emitOp(OpNil)
+	}
+	c.defineVariable(stmt.Name.Lexeme)
+	return nil
+}
+
+// VisitBlockStmt ...
+func (c *Compiler) VisitBlockStmt(stmt *BlockStmt) interface{} {
+	c.beginScope()
+	for _, statement := range stmt.Statements {
+		c.statement(statement)
+	}
+	c.endScope()
+	return nil
+}
+
+// VisitIfStmt ...
+func (c *Compiler) VisitIfStmt(stmt *IfStmt) interface{} {
+	c.expression(stmt.Condition)
+	thenJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	c.statement(stmt.ThenBranch)
+	elseJump := c.emitJump(OpJump)
+	c.patchJump(thenJump)
+	c.emitOp(OpPop)
+	c.statement(stmt.ElseBranch)
+	c.patchJump(elseJump)
+	return nil
+}
+
+// VisitWhileStmt ...
+func (c *Compiler) VisitWhileStmt(stmt *WhileStmt) interface{} {
+	loopStart := len(c.chunk().Code)
+	c.expression(stmt.Condition)
+	exitJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	c.statement(stmt.Body)
+	c.emitLoop(loopStart)
+	c.patchJump(exitJump)
+	c.emitOp(OpPop)
+	return nil
+}
+
+// VisitFunctionStmt ...
+func (c *Compiler) VisitFunctionStmt(stmt *FunctionStmt) interface{} {
+	c.token = stmt.Name
+	if stmt.IsGenerator {
+		c.unsupported(stmt.Name.Line, "Generator function")
+		return nil
+	}
+	if stmt.IsAsync {
+		c.unsupported(stmt.Name.Line, "Async function")
+		return nil
+	}
+	// A local function is declared before its body is compiled so that it
+	// can refer to itself.
+	if c.scopeDepth > 0 {
+		c.addLocal(stmt.Name.Lexeme)
+	}
+
+	fc := newCompiler(c, stmt.Name.Lexeme, false)
+	fc.beginScope()
+	fc.function.Arity = len(stmt.Params)
+	for _, param := range stmt.Params {
+		fc.addLocal(param.Lexeme)
+	}
+	for _, statement := range stmt.Body {
+		fc.statement(statement)
+	}
+	fc.emitReturn()
+	c.errors = append(c.errors, fc.errors...)
+
+	c.emitShort(OpClosure, c.makeConstant(fc.function))
+	for _, upvalue := range fc.upvalues {
+		isLocal := byte(0)
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		c.emit(isLocal, byte(upvalue.index))
+	}
+	if c.scopeDepth == 0 {
+		c.emitShort(OpDefineGlobal, c.makeConstant(stmt.Name.Lexeme))
+	}
+	return nil
+}
+
+// VisitReturnStmt ...
+func (c *Compiler) VisitReturnStmt(stmt *ReturnStmt) interface{} {
+	c.token = stmt.Keyword
+	if c.isScript {
+		c.errorAt(stmt.Keyword.Line, "Can't return from top-level code.")
+		return nil
+	}
+	if stmt.Value != nil {
+		c.expression(stmt.Value)
+	} else {
+		c.emitOp(OpNil)
+	}
+	c.emitOp(OpReturn)
+	return nil
+}
+
+// VisitYieldStmt ...
+func (c *Compiler) VisitYieldStmt(stmt *YieldStmt) interface{} {
+	c.unsupported(stmt.Keyword.Line, "yield")
+	return nil
+}
+
+// VisitSpawnStmt ...
+func (c *Compiler) VisitSpawnStmt(stmt *SpawnStmt) interface{} {
+	c.unsupported(stmt.Keyword.Line, "spawn")
+	return nil
+}
+
+// VisitSelectStmt ...
+func (c *Compiler) VisitSelectStmt(stmt *SelectStmt) interface{} {
+	c.unsupported(stmt.Keyword.Line, "select")
+	return nil
+}
+
+// VisitLiteralExpr ...
+func (c *Compiler) VisitLiteralExpr(expr *ExprLiteral) interface{} {
+	switch expr.Value {
+	case nil:
+		c.emitOp(OpNil)
+	case true:
+		c.emitOp(OpTrue)
+	case false:
+		c.emitOp(OpFalse)
+	default:
+		c.emitConstant(expr.Value)
+	}
+	return nil
+}
+
+// VisitGroupingExpr ...
+func (c *Compiler) VisitGroupingExpr(expr *ExprGrouping) interface{} {
+	c.expression(expr.Expr)
+	return nil
+}
+
+// VisitVarExpr ...
+func (c *Compiler) VisitVarExpr(expr *ExprVar) interface{} {
+	c.token = expr.Name
+	c.namedVariable(expr.Name.Lexeme, false)
+	return nil
+}
+
+// VisitAssignExpr ...
+func (c *Compiler) VisitAssignExpr(expr *ExprAssign) interface{} {
+	c.expression(expr.Value)
+	c.token = expr.Name
+	c.namedVariable(expr.Name.Lexeme, true)
+	return nil
+}
+
+// VisitUnaryExpr ...
+func (c *Compiler) VisitUnaryExpr(expr *ExprUnary) interface{} {
+	c.expression(expr.Right)
+	c.token = expr.Operator
+	switch expr.Operator.Type {
+	case TokenTypeBang:
+		c.emitOp(OpNot)
+	case TokenTypeMinus:
+		c.emitOp(OpNegate)
+	}
+	return nil
+}
+
+var binaryOps = map[TokenType]OpCode{
+	TokenTypeEqualEqual:   OpEqual,
+	TokenTypeBangEqual:    OpNotEqual,
+	TokenTypeGreater:      OpGreater,
+	TokenTypeGreaterEqual: OpGreaterEqual,
+	TokenTypeLess:         OpLess,
+	TokenTypeLessEqual:    OpLessEqual,
+	TokenTypePlus:         OpAdd,
+	TokenTypeMinus:        OpSubtract,
+	TokenTypeStar:         OpMultiply,
+	TokenTypeSlash:        OpDivide,
+	TokenTypeDotDot:       OpRange,
+	TokenTypeDotDotEqual:  OpRangeInclusive,
+}
+
+// VisitBinaryExpr ...
+func (c *Compiler) VisitBinaryExpr(expr *ExprBinary) interface{} {
+	c.expression(expr.Left)
+	c.expression(expr.Right)
+	c.token = expr.Operator
+	c.emitOp(binaryOps[expr.Operator.Type])
+	return nil
+}
+
+// VisitLogicalExpr ...
+func (c *Compiler) VisitLogicalExpr(expr *ExprLogical) interface{} {
+	c.expression(expr.Left)
+	c.token = expr.Operator
+	if expr.Operator.Type == TokenTypeOr {
+		elseJump := c.emitJump(OpJumpIfFalse)
+		endJump := c.emitJump(OpJump)
+		c.patchJump(elseJump)
+		c.emitOp(OpPop)
+		c.expression(expr.Right)
+		c.patchJump(endJump)
+		return nil
+	}
+	endJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	c.expression(expr.Right)
+	c.patchJump(endJump)
+	return nil
+}
+
+// VisitCallExpr ...
+func (c *Compiler) VisitCallExpr(expr *ExprCall) interface{} {
+	c.expression(expr.Callee)
+	for _, arg := range expr.Arguments {
+		c.expression(*arg)
+	}
+	c.token = expr.Paren
+	if len(expr.Arguments) > 255 {
+		c.errorAt(expr.Paren.Line, "Can't have more than 255 arguments.")
+	}
+	c.emitOp(OpCall, byte(len(expr.Arguments)))
+	return nil
+}
+
+// VisitGetExpr ...
+func (c *Compiler) VisitGetExpr(expr *ExprGet) interface{} {
+	c.expression(expr.Object)
+	c.token = expr.Name
+	c.emitShort(OpGetProperty, c.makeConstant(expr.Name.Lexeme))
+	return nil
+}
+
+// VisitSetExpr ...
+func (c *Compiler) VisitSetExpr(expr *ExprSet) interface{} {
+	c.expression(expr.Object)
+	c.expression(expr.Value)
+	c.token = expr.Name
+	c.emitShort(OpSetProperty, c.makeConstant(expr.Name.Lexeme))
+	return nil
+}
+
+// VisitAwaitExpr ...
+func (c *Compiler) VisitAwaitExpr(expr *ExprAwait) interface{} {
+	c.unsupported(expr.Keyword.Line, "await")
+	return nil
+}
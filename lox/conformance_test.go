@@ -0,0 +1,209 @@
+package lox
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The conformance tests are the .lox files under testdata/conformance, one
+// directory per feature. They are annotated in the format of the test suite
+// of Crafting Interpreters:
+//
+//	print 1; // expect: 1
+//	-"s"; // expect runtime error: Operand must be a number.
+//	var false; // Error at 'false': Expect variable name.
+//	// [line 3] Error: Unterminated string.
+//
+// Files run in the strict dialect unless they contain "// dialect: extended".
+// Run with -v for the per-feature report.
+const conformanceDir = "testdata/conformance"
+
+var (
+	expectOutput       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectRuntimeError = regexp.MustCompile(`// expect runtime error: (.+)`)
+	expectError        = regexp.MustCompile(`// (Error.*)`)
+	expectErrorLine    = regexp.MustCompile(`// \[line (\d+)\] (Error.*)`)
+	extendedDialect    = regexp.MustCompile(`(?m)^// dialect: extended$`)
+	// supplementaryLine matches the lines of stderr that add to an error:
+	// notes and tracebacks.
+	supplementaryLine = regexp.MustCompile(`^(\[[^\]]*\] (in |Note: )|Note: |\[Previous frame repeated)`)
+)
+
+// expectations are what running a conformance test must produce.
+type expectations struct {
+	output       []string
+	errors       []string
+	runtimeError string
+	runtimeLine  int
+	exitCode     int
+}
+
+func parseExpectations(source string) expectations {
+	var e expectations
+	for idx, line := range strings.Split(source, "\n") {
+		lineNumber := idx + 1
+		if m := expectOutput.FindStringSubmatch(line); m != nil {
+			e.output = append(e.output, m[1])
+		} else if m := expectRuntimeError.FindStringSubmatch(line); m != nil {
+			e.runtimeError, e.runtimeLine, e.exitCode = m[1], lineNumber, 70
+		} else if m := expectErrorLine.FindStringSubmatch(line); m != nil {
+			e.errors = append(e.errors, fmt.Sprintf("[line %s] %s", m[1], m[2]))
+			e.exitCode = 65
+		} else if m := expectError.FindStringSubmatch(line); m != nil {
+			e.errors = append(e.errors, fmt.Sprintf("[line %d] %s", lineNumber, m[1]))
+			e.exitCode = 65
+		}
+	}
+	return e
+}
+
+// conformanceEngines run statements as the command line does, returning
+// the diagnostics of the run.
+var conformanceEngines = []struct {
+	name string
+	run  func(i *Interpreter, stmts []Stmt) Diagnostics
+}{
+	{"tree", func(i *Interpreter, stmts []Stmt) Diagnostics { return i.Interpret(stmts) }},
+	{"vm", func(i *Interpreter, stmts []Stmt) Diagnostics { return NewVM(i).Interpret(stmts) }},
+	{"closure", func(i *Interpreter, stmts []Stmt) Diagnostics {
+		program, errs := CompileClosures(stmts)
+		if len(errs) > 0 {
+			return ErrorList(errs).Diagnostics()
+		}
+		return program.Run(i)
+	}},
+}
+
+// runConformance runs source as the command line does with -plain, and
+// returns what it writes to stdout and stderr and its exit code.
+func runConformance(run func(*Interpreter, []Stmt) Diagnostics, source string) (string, string, int) {
+	dialect := DialectStrict
+	if extendedDialect.MatchString(source) {
+		dialect = DialectExtended
+	}
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	renderer := &Renderer{Source: source, Plain: true}
+	i := NewInterpreter(WithDialect(dialect), WithStdout(&stdout), WithStderr(&stderr), WithRenderer(renderer), WithStdin(strings.NewReader("")))
+	stmts, err := i.Parse("", source)
+	var ds Diagnostics
+	if err != nil {
+		ds = err.(ErrorList).Diagnostics()
+		renderer.RenderAll(&stderr, ds)
+	} else {
+		ds = run(i, stmts)
+	}
+	return stdout.String(), stderr.String(), ds.ExitCode()
+}
+
+// lines splits output into lines, without the newline ending the last.
+func lines(output string) []string {
+	if output == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+}
+
+// checkConformance returns how the result of a run differs from e.
+func checkConformance(e expectations, stdout string, stderr string, exitCode int) []string {
+	var failures []string
+	output := lines(stdout)
+	for idx := 0; idx < len(output) || idx < len(e.output); idx++ {
+		switch {
+		case idx >= len(e.output):
+			failures = append(failures, fmt.Sprintf("unexpected output %q", output[idx]))
+		case idx >= len(output):
+			failures = append(failures, fmt.Sprintf("missing output %q", e.output[idx]))
+		case output[idx] != e.output[idx]:
+			failures = append(failures, fmt.Sprintf("got output %q, expected %q", output[idx], e.output[idx]))
+		}
+	}
+
+	expected := e.errors
+	if e.runtimeError != "" {
+		expected = []string{fmt.Sprintf("[line %d] Error: %s", e.runtimeLine, e.runtimeError)}
+	}
+	errors := make([]string, 0)
+	for _, line := range lines(stderr) {
+		if !supplementaryLine.MatchString(line) {
+			errors = append(errors, line)
+		}
+	}
+	if strings.Join(errors, "\n") != strings.Join(expected, "\n") {
+		failures = append(failures, fmt.Sprintf("got errors %q, expected %q", errors, expected))
+	}
+
+	if exitCode != e.exitCode {
+		failures = append(failures, "got exit code "+strconv.Itoa(exitCode)+", expected "+strconv.Itoa(e.exitCode))
+	}
+	return failures
+}
+
+// TestConformance runs the conformance tests in every engine.
+func TestConformance(t *testing.T) {
+	var files []string
+	err := filepath.Walk(conformanceDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && filepath.Ext(path) == ".lox" {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no tests in %s", conformanceDir)
+	}
+
+	// passed and total count the tests per feature and engine.
+	passed := make(map[string]map[string]int)
+	total := make(map[string]map[string]int)
+	for _, path := range files {
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		source := string(bytes)
+		e := parseExpectations(source)
+		rel, _ := filepath.Rel(conformanceDir, path)
+		feature := filepath.ToSlash(filepath.Dir(rel))
+		if passed[feature] == nil {
+			passed[feature], total[feature] = make(map[string]int), make(map[string]int)
+		}
+		for _, engine := range conformanceEngines {
+			total[feature][engine.name]++
+			stdout, stderr, exitCode := runConformance(engine.run, source)
+			failures := checkConformance(e, stdout, stderr, exitCode)
+			if len(failures) == 0 {
+				passed[feature][engine.name]++
+				continue
+			}
+			t.Errorf("%s %s:\n\t%s", engine.name, filepath.ToSlash(rel), strings.Join(failures, "\n\t"))
+		}
+	}
+
+	features := make([]string, 0, len(total))
+	for feature := range total {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	report := bytes.Buffer{}
+	fmt.Fprintf(&report, "%-20s", "feature")
+	for _, engine := range conformanceEngines {
+		fmt.Fprintf(&report, " %8s", engine.name)
+	}
+	for _, feature := range features {
+		fmt.Fprintf(&report, "\n%-20s", feature)
+		for _, engine := range conformanceEngines {
+			fmt.Fprintf(&report, " %8s", fmt.Sprintf("%d/%d", passed[feature][engine.name], total[feature][engine.name]))
+		}
+	}
+	t.Logf("conformance:\n%s", report.String())
+}
@@ -0,0 +1,90 @@
+package lox
+
+// coroutine runs a body on its own goroutine while keeping execution
+// strictly sequential: control passes back and forth between the resumer
+// and the body, so only one side runs at any time.
+type coroutine struct {
+	body      func(co *coroutine) interface{}
+	in        chan interface{}
+	out       chan coroutineMsg
+	cancelled chan struct{}
+	started   bool
+	finished  bool
+}
+
+type coroutineMsg struct {
+	value    interface{}
+	done     bool
+	panicked interface{}
+}
+
+// coroutineCancelled unwinds the body of a cancelled coroutine.
+type coroutineCancelled struct{}
+
+func newCoroutine(body func(co *coroutine) interface{}) *coroutine {
+	return &coroutine{
+		body:      body,
+		in:        make(chan interface{}),
+		out:       make(chan coroutineMsg),
+		cancelled: make(chan struct{}),
+	}
+}
+
+// resume runs the body until its next yield and returns the yielded value
+// and true, or the body's result and false once it has finished. A panic in
+// the body is re-raised on the resumer's goroutine.
+func (co *coroutine) resume(value interface{}) (interface{}, bool) {
+	if co.finished {
+		return nil, false
+	}
+	if !co.started {
+		co.started = true
+		go co.run()
+	} else {
+		co.in <- value
+	}
+	msg := <-co.out
+	if msg.panicked != nil {
+		co.finished = true
+		panic(msg.panicked)
+	}
+	if msg.done {
+		co.finished = true
+		return msg.value, false
+	}
+	return msg.value, true
+}
+
+func (co *coroutine) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(coroutineCancelled); ok {
+				return
+			}
+			co.out <- coroutineMsg{panicked: r}
+		}
+	}()
+	result := co.body(co)
+	co.out <- coroutineMsg{value: result, done: true}
+}
+
+// yield is called by the body to hand value to the resumer. It returns the
+// value passed to the next resume.
+func (co *coroutine) yield(value interface{}) interface{} {
+	co.out <- coroutineMsg{value: value}
+	select {
+	case resumed := <-co.in:
+		return resumed
+	case <-co.cancelled:
+		panic(coroutineCancelled{})
+	}
+}
+
+// cancel releases the goroutine of a suspended coroutine. It is a no-op if
+// the coroutine never started or has already finished.
+func (co *coroutine) cancel() {
+	if co.started && !co.finished {
+		co.finished = true
+		close(co.cancelled)
+	}
+}
@@ -0,0 +1,154 @@
+package lox
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Severity ...
+type Severity int
+
+// Severities of diagnostics.
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+var severityNames = [...]string{"error", "warning", "note"}
+
+// String ...
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// Phase is the stage of running a program that produced a diagnostic.
+type Phase int
+
+// Phases of running a program.
+const (
+	PhaseScan Phase = iota
+	PhaseParse
+	PhaseCompile
+	PhaseRuntime
+)
+
+var phaseNames = [...]string{"scan", "parse", "compile", "runtime"}
+
+// String ...
+func (p Phase) String() string {
+	return phaseNames[p]
+}
+
+// Diagnostic describes an error found in a program. Where says what in the
+// span the message is about, such as "at 'x'", and may be empty.
+type Diagnostic struct {
+	Severity Severity
+	Phase    Phase
+	Message  string
+	Where    string
+	Span     Span
+	Notes    []Note
+	// Trace is the traceback of a runtime error raised inside a function.
+	Trace []Frame
+}
+
+// Note adds information to a diagnostic, such as where a variable was
+// declared. Its span may be zero.
+type Note struct {
+	Message string
+	Span    Span
+}
+
+// Error ...
+func (d Diagnostic) Error() string {
+	where := ""
+	if d.Where != "" {
+		where = " " + d.Where
+	}
+	return report(d.Span.Line, where, d.Message) + traceback(d.Trace)
+}
+
+// Diagnostics is the list of diagnostics of a program, in the order they
+// were found.
+type Diagnostics []Diagnostic
+
+// HasErrors reports whether any of the diagnostics is an error from one of
+// phases, or from any phase when none are given.
+func (ds Diagnostics) HasErrors(phases ...Phase) bool {
+	for _, d := range ds {
+		if d.Severity != SeverityError {
+			continue
+		}
+		if len(phases) == 0 {
+			return true
+		}
+		for _, phase := range phases {
+			if d.Phase == phase {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExitCode returns the exit status of the command line after a run with
+// the diagnostics ds: 65 (EX_DATAERR) if the program was rejected, 70
+// (EX_SOFTWARE) if it failed while running, and 0 otherwise.
+func (ds Diagnostics) ExitCode() int {
+	switch {
+	case ds.HasErrors(PhaseScan, PhaseParse, PhaseCompile):
+		return 65
+	case ds.HasErrors(PhaseRuntime):
+		return 70
+	}
+	return 0
+}
+
+// String ...
+func (ds Diagnostics) String() string {
+	buf := bytes.Buffer{}
+	for _, d := range ds {
+		buf.WriteString(d.Error())
+	}
+	return buf.String()
+}
+
+// Diagnose returns the diagnostic describing err. Errors that are not from
+// this package are runtime errors without a position.
+func Diagnose(err error) Diagnostic {
+	switch e := err.(type) {
+	case Diagnostic:
+		return e
+	case *LexError:
+		span := e.Span
+		span.Line = e.Line
+		return Diagnostic{Phase: PhaseScan, Message: e.Msg, Span: span}
+	case *ParseError:
+		where := fmt.Sprintf("at '%s'", e.Token.Lexeme)
+		if e.Token.Type == TokenTypeEOF {
+			where = "at end"
+		}
+		return Diagnostic{Phase: PhaseParse, Message: e.Msg, Where: where, Span: e.Token.Span(), Notes: e.Notes}
+	case *CompileError:
+		return Diagnostic{Phase: PhaseCompile, Message: e.Msg, Span: Span{Line: e.Line}}
+	case *RuntimeError:
+		span := Span{Line: e.Line}
+		if e.Token != nil {
+			span = e.Token.Span()
+		}
+		return Diagnostic{Phase: PhaseRuntime, Message: e.Msg, Span: span, Trace: e.Trace}
+	case *LimitError:
+		return Diagnostic{Phase: PhaseRuntime, Message: e.Err.Error(), Span: Span{Line: e.Line}}
+	}
+	return Diagnostic{Phase: PhaseRuntime, Message: err.Error()}
+}
+
+// Diagnostics returns the diagnostics of the errors in the list.
+func (el ErrorList) Diagnostics() Diagnostics {
+	ds := make(Diagnostics, len(el))
+	for idx, e := range el {
+		ds[idx] = Diagnose(e)
+	}
+	return ds
+}
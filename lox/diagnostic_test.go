@@ -0,0 +1,35 @@
+package lox
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestDiagnostics ...
+func TestDiagnostics(t *testing.T) {
+	_, err := Parse("var s = \"open;")
+	ds := err.(ErrorList).Diagnostics()
+	if ds[0].Phase != PhaseScan || ds[0].Span.Line != 1 || !ds.HasErrors(PhaseScan) || ds.HasErrors(PhaseRuntime) {
+		t.Errorf("got %#v, expected a scan error first", ds)
+	}
+
+	_, err = Parse("print 1;\nprint 2 +;")
+	ds = err.(ErrorList).Diagnostics()
+	if len(ds) != 1 || ds[0].Phase != PhaseParse || ds[0].Where != "at ';'" || ds[0].Span.Line != 2 {
+		t.Errorf("got %#v, expected a parse error at ';' on line 2", ds)
+	}
+
+	stderr := bytes.Buffer{}
+	i := NewInterpreter(WithStderr(&stderr))
+	stmts, _ := Parse("missing;\nclose(1);")
+	ds = i.Interpret(stmts)
+	if len(ds) != 1 || !ds.HasErrors(PhaseRuntime) || ds[0].Span.Line != 1 || ds[0].Span.Column != 1 {
+		t.Errorf("got %#v, expected a runtime error at 'missing' that stops the script", ds)
+	}
+	if stderr.Len() == 0 {
+		t.Error("expected the errors to be printed")
+	}
+	if ds = i.Interpret(stmts[:0]); len(ds) != 0 {
+		t.Errorf("got %#v, expected no diagnostics for a new run", ds)
+	}
+}
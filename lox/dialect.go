@@ -0,0 +1,60 @@
+package lox
+
+import "fmt"
+
+// Dialect is the variant of Lox an interpreter runs.
+type Dialect int
+
+const (
+	// DialectExtended is golox: Lox with ranges, generators, async
+	// functions, tasks and select. Empty strings and zero are falsy, and
+	// '+' converts a value concatenated with a string.
+	DialectExtended Dialect = iota
+	// DialectStrict is Lox as the book defines it, so that scripts behave
+	// as they do in other implementations: only nil and false are falsy,
+	// '+' takes two numbers or two strings, and the keywords of the
+	// extensions are plain identifiers.
+	DialectStrict
+)
+
+var dialectNames = [...]string{"extended", "strict"}
+
+// String ...
+func (d Dialect) String() string {
+	return dialectNames[d]
+}
+
+// ParseDialect returns the dialect with the given name.
+func ParseDialect(name string) (Dialect, error) {
+	for idx, dialectName := range dialectNames {
+		if name == dialectName {
+			return Dialect(idx), nil
+		}
+	}
+	return DialectExtended, fmt.Errorf("unknown dialect %q", name)
+}
+
+// extensionKeywords are the keywords golox adds to Lox.
+var extensionKeywords = map[TokenType]bool{
+	TokenTypeAsync:   true,
+	TokenTypeAwait:   true,
+	TokenTypeCase:    true,
+	TokenTypeDefault: true,
+	TokenTypeSelect:  true,
+	TokenTypeSpawn:   true,
+	TokenTypeYield:   true,
+}
+
+// WithDialect sets the dialect of the scripts the interpreter parses and
+// runs. The default is DialectExtended.
+func WithDialect(d Dialect) Option {
+	return func(i *Interpreter) {
+		i.dialect = d
+	}
+}
+
+// Parse is ParseFile in the dialect of the interpreter. In DialectStrict it
+// also reports the errors the book's resolver does.
+func (i *Interpreter) Parse(file string, source string) ([]Stmt, error) {
+	return parse(file, source, i.dialect)
+}
@@ -0,0 +1,112 @@
+package lox
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestStrictDialect runs book Lox in every engine: only nil and false are
+// falsy, and the keywords of the extensions are identifiers.
+func TestStrictDialect(t *testing.T) {
+	source := `
+var spawn = 0;
+if (spawn) print "zero is true"; else print "zero is false";
+if ("") print "empty is true"; else print "empty is false";
+print !nil;
+`
+	if _, err := NewInterpreter().Parse("", source); err == nil {
+		t.Error("expected the extended dialect to reject 'var spawn'")
+	}
+	stmts, err := NewInterpreter(WithDialect(DialectStrict)).Parse("", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, run := range engines(stmts) {
+		stdout := bytes.Buffer{}
+		if ds := run(NewInterpreter(WithDialect(DialectStrict), WithStdout(&stdout))); len(ds) > 0 {
+			t.Fatalf("%s: %v", name, ds)
+		}
+		if got, want := stdout.String(), "zero is true\nempty is true\ntrue\n"; got != want {
+			t.Errorf("%s: got %q, expected %q", name, got, want)
+		}
+	}
+}
+
+// TestDialectOperators checks the results of operators that differ between
+// the dialects.
+func TestDialectOperators(t *testing.T) {
+	tests := []struct {
+		source   string
+		extended string
+		strict   string
+	}{
+		{`"a" + 1;`, "a1", "Operands must be two numbers or two strings."},
+		{`"" or "x";`, "x", ""},
+		{`0 and 1;`, "0", "1"},
+		{`list(1..3);`, "[1, 2]", "Ranges are not part of strict Lox."},
+	}
+	for _, test := range tests {
+		for dialect, want := range map[Dialect]string{DialectExtended: test.extended, DialectStrict: test.strict} {
+			value, err := NewInterpreter(WithDialect(dialect)).Eval(test.source)
+			got := Stringify(value)
+			if err != nil {
+				got = err.Error()
+			}
+			if got != want && (err == nil || !strings.Contains(got, want)) {
+				t.Errorf("%s: %s got %q, expected %q", dialect, test.source, got, want)
+			}
+		}
+	}
+}
+
+// TestStrictResolver checks that the strict dialect reports the static
+// errors of the book, which the extended dialect allows, with a note at the
+// declaration of the variable.
+func TestStrictResolver(t *testing.T) {
+	tests := []struct {
+		source   string
+		err      string
+		declared Span
+	}{
+		{"{\n  var a = 1;\n  var a = 2;\n}", "[line 3] Error at 'a': Already a variable with this name in this scope.\n", Span{Line: 2, Column: 7, Start: 8, End: 9}},
+		{"fun f(a, a) {}", "[line 1] Error at 'a': Already a variable with this name in this scope.\n", Span{Line: 1, Column: 7, Start: 6, End: 7}},
+		{"var a = 1;\n{\n  var a = a;\n}", "[line 3] Error at 'a': Can't read local variable in its own initializer.\n", Span{Line: 3, Column: 7, Start: 19, End: 20}},
+	}
+	for _, test := range tests {
+		_, err := NewInterpreter(WithDialect(DialectStrict)).Parse("", test.source)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: got %v, expected %q", test.source, err, test.err)
+			continue
+		}
+		notes := err.(ErrorList).Diagnostics()[0].Notes
+		if len(notes) != 1 || notes[0].Message != "variable declared here" || notes[0].Span != test.declared {
+			t.Errorf("%q: got notes %v, expected one at %v", test.source, notes, test.declared)
+		}
+		if _, err := NewInterpreter().Parse("", test.source); err != nil {
+			t.Errorf("%q: got %v in the extended dialect", test.source, err)
+		}
+	}
+}
+
+// TestStrictUndefinedGlobal checks that reading an undefined global in the
+// strict dialect is a runtime error that stops the script with exit code 70.
+func TestStrictUndefinedGlobal(t *testing.T) {
+	stmts, err := NewInterpreter(WithDialect(DialectStrict)).Parse("", "print \"before\";\nprint missing;\nprint \"after\";")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, run := range engines(stmts) {
+		stdout := bytes.Buffer{}
+		ds := run(NewInterpreter(WithDialect(DialectStrict), WithStdout(&stdout)))
+		if len(ds) != 1 || ds[0].Message != "Undefined variable 'missing'." || ds[0].Span.Line != 2 {
+			t.Errorf("%s: got %v, expected an undefined variable error on line 2", name, ds)
+		}
+		if code := ds.ExitCode(); code != 70 {
+			t.Errorf("%s: exit code %d, expected 70", name, code)
+		}
+		if got := stdout.String(); got != "before\n" {
+			t.Errorf("%s: got %q, expected the script to stop at the error", name, got)
+		}
+	}
+}
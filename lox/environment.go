@@ -1,13 +1,21 @@
 package lox
 
 import (
-	"fmt"
+	"sync"
 )
 
-// Environment ...
+// Environment holds variables. The global environment keeps them by name
+// in Values; local scopes keep them in Slots, at the indexes assigned by
+// the Resolver.
+//
+// Only the task that created a local scope can reach it until a function
+// closes over it, so slots are locked only once the scope is shared.
 type Environment struct {
 	Enclosing *Environment
 	Values    map[string]interface{}
+	Slots     []interface{}
+	mu        sync.RWMutex
+	shared    bool
 }
 
 // NewEnvironment ...
@@ -18,33 +26,90 @@ func NewEnvironment(enclosing *Environment) *Environment {
 	return ne
 }
 
+// NewScope creates a local environment with room for size variables.
+func NewScope(enclosing *Environment, size int) *Environment {
+	return &Environment{Enclosing: enclosing, Slots: make([]interface{}, size)}
+}
+
 // Define ...
-func (e Environment) Define(name string, value interface{}) {
+func (e *Environment) Define(name string, value interface{}) {
+	e.mu.Lock()
 	e.Values[name] = value
+	e.mu.Unlock()
 }
 
-// Get ...
-func (e Environment) Get(name string) interface{} {
-	if value, ok := e.Values[name]; ok {
-		return value
+// Lookup returns the value of the variable name and whether it is defined.
+func (e *Environment) Lookup(name string) (interface{}, bool) {
+	e.mu.RLock()
+	value, ok := e.Values[name]
+	e.mu.RUnlock()
+	if ok {
+		return value, true
 	}
 	if e.Enclosing != nil {
-		return e.Enclosing.Get(name)
+		return e.Enclosing.Lookup(name)
 	}
-	fmt.Println(&VarError{Name: name, Msg: "Undefined variable"})
-	return nil
+	return nil, false
 }
 
-// Assign ...
-func (e Environment) Assign(name string, value interface{}) {
+// Get returns the value of the variable name, or nil if it is undefined.
+func (e *Environment) Get(name string) interface{} {
+	value, _ := e.Lookup(name)
+	return value
+}
+
+// Assign sets the variable name and reports whether it is defined. An
+// undefined variable is left undefined.
+func (e *Environment) Assign(name string, value interface{}) bool {
+	e.mu.Lock()
 	if _, ok := e.Values[name]; ok {
 		e.Values[name] = value
-		return
+		e.mu.Unlock()
+		return true
 	}
+	e.mu.Unlock()
 	if e.Enclosing != nil {
-		e.Enclosing.Assign(name, value)
-		return
+		return e.Enclosing.Assign(name, value)
+	}
+	return false
+}
+
+// share marks e and the scopes enclosing it as reachable from closures,
+// which other tasks may call. It must be called before the closure leaves
+// the task that created it.
+func (e *Environment) share() {
+	for ; e != nil && !e.shared; e = e.Enclosing {
+		e.shared = true
 	}
+}
 
-	fmt.Println(&VarError{Name: name, Msg: "Undefined variable"})
+func (e *Environment) ancestor(depth int) *Environment {
+	for ; depth > 0; depth-- {
+		e = e.Enclosing
+	}
+	return e
+}
+
+// GetAt reads the local variable in slot of the environment depth levels up.
+func (e *Environment) GetAt(depth int, slot int) interface{} {
+	env := e.ancestor(depth)
+	if !env.shared {
+		return env.Slots[slot]
+	}
+	env.mu.RLock()
+	value := env.Slots[slot]
+	env.mu.RUnlock()
+	return value
+}
+
+// AssignAt is the counterpart of GetAt that sets the variable.
+func (e *Environment) AssignAt(depth int, slot int, value interface{}) {
+	env := e.ancestor(depth)
+	if !env.shared {
+		env.Slots[slot] = value
+		return
+	}
+	env.mu.Lock()
+	env.Slots[slot] = value
+	env.mu.Unlock()
 }
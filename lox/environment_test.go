@@ -0,0 +1,108 @@
+package lox
+
+import (
+	"testing"
+)
+
+// scopesProgram reads variables through closures and shadowing.
+const scopesProgram = `
+var a = "global";
+var result = "";
+{
+  fun show() { result = result + a; }
+  show();
+  var a = "block";
+  show();
+  {
+    var a = a + " inner";
+    result = result + " " + a;
+  }
+}
+fun counter() {
+  var count = 0;
+  fun increment() { count = count + 1; return count; }
+  return increment;
+}
+var c = counter();
+c();
+var counted = c();
+`
+
+// TestResolvedScopes ...
+func TestResolvedScopes(t *testing.T) {
+	i := interpret(scopesProgram)
+	if got := i.GlobalEnv.Get("result"); got != "globalglobal block inner" {
+		t.Errorf("got %v, expected globalglobal block inner", got)
+	}
+	if got := i.GlobalEnv.Get("counted"); got != 2.0 {
+		t.Errorf("got %v, expected 2", got)
+	}
+}
+
+// benchmarkLookup runs source with its locals in resolved slots, then
+// unresolved, which looks every variable up by name in the global map.
+// Both must leave the same result.
+func benchmarkLookup(b *testing.B, source string, result float64) {
+	for _, bench := range []struct {
+		name  string
+		stmts []Stmt
+	}{
+		{"slots", mustParse(source)},
+		{"map", NewParser(NewScanner(source).ScanTokens()).Parse()},
+	} {
+		stmts := bench.stmts
+		b.Run(bench.name, func(b *testing.B) {
+			i := NewInterpreter()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				if ds := i.Interpret(stmts); len(ds) > 0 {
+					b.Fatal(ds)
+				}
+			}
+			b.StopTimer()
+			if got := i.GlobalEnv.Get("result"); got != result {
+				b.Fatalf("got %v, expected %v", got, result)
+			}
+		})
+	}
+}
+
+// BenchmarkDeepScope reads variables declared several scopes up.
+func BenchmarkDeepScope(b *testing.B) {
+	benchmarkLookup(b, `
+var result;
+{
+  var a = 1;
+  {
+    var b = 2;
+    {
+      var c = 3;
+      {
+        var d = 4;
+        {
+          var sum = 0;
+          for (var i = 0; i < 2000; i = i + 1) {
+            var e = i;
+            sum = sum + a + b + c + d + e;
+          }
+          result = sum;
+        }
+      }
+    }
+  }
+}
+`, 2019000)
+}
+
+// BenchmarkTightLoop reads and assigns two locals of one scope.
+func BenchmarkTightLoop(b *testing.B) {
+	benchmarkLookup(b, `
+var result;
+{
+  var sum = 0;
+  for (var i = 0; i < 10000; i = i + 1) sum = sum + i;
+  result = sum;
+}
+`, 49995000)
+}
@@ -5,30 +5,62 @@ import (
 )
 
 func report(line int, where string, message string) string {
-	return fmt.Sprintf("[line %d ] Error %s: %s\n", line, where, message)
+	if line == 0 {
+		return fmt.Sprintf("Error%s: %s\n", where, message)
+	}
+	return fmt.Sprintf("[line %d] Error%s: %s\n", line, where, message)
 }
 
 // RuntimeError ...
 type RuntimeError struct {
 	Line int
 	Msg  string
+	// Token, if set, is the operator or name the error is about.
+	Token *Token
+	// Trace lists the calls in progress when the error was raised inside a
+	// function, innermost first.
+	Trace []Frame
+}
+
+// runtimeErr returns a runtime error about t.
+func runtimeErr(t Token, message string) *RuntimeError {
+	return &RuntimeError{Line: t.Line, Msg: message, Token: &t}
+}
+
+// LexError ...
+type LexError struct {
+	Line int
+	Msg  string
+	// Span is where the offending token starts, up to the error.
+	Span Span
 }
 
 // ParseError ...
 type ParseError struct {
 	Token Token
 	Msg   string
+	Notes []Note
 }
 
-// VarError ...
-type VarError struct {
-	Name string
+// CompileError ...
+type CompileError struct {
+	Line int
 	Msg  string
 }
 
 // Error ...
 func (re *RuntimeError) Error() string {
-	return report(re.Line, "", re.Msg)
+	return report(re.Line, "", re.Msg) + traceback(re.Trace)
+}
+
+// Error ...
+func (le *LexError) Error() string {
+	return report(le.Line, "", le.Msg)
+}
+
+// Error ...
+func (ce *CompileError) Error() string {
+	return report(ce.Line, "", ce.Msg)
 }
 
 // Error ...
@@ -38,8 +70,3 @@ func (pe *ParseError) Error() string {
 	}
 	return report(pe.Token.Line, fmt.Sprintf(" at '%s'", pe.Token.Lexeme), pe.Msg)
 }
-
-// Error ...
-func (ve *VarError) Error() string {
-	return fmt.Sprintf("Error:: Variable name: %s, Message: %s", ve.Name, ve.Msg)
-}
@@ -0,0 +1,226 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// Value is a Lox value as seen from Go: nil, float64, string, bool, or one
+// of the runtime types of this package.
+type Value = interface{}
+
+// ErrorList holds the errors of one evaluation. Its elements are
+// *LexError, *ParseError, *RuntimeError or *DeadlockError, or the error of
+// the context that stopped the evaluation.
+type ErrorList []error
+
+// Error ...
+func (el ErrorList) Error() string {
+	buf := bytes.Buffer{}
+	for _, e := range el {
+		s := e.Error()
+		buf.WriteString(s)
+		if len(s) == 0 || s[len(s)-1] != '\n' {
+			buf.WriteRune('\n')
+		}
+	}
+	return buf.String()
+}
+
+// Unwrap lets errors.Is and errors.As look at every error in the list.
+func (el ErrorList) Unwrap() []error {
+	return el
+}
+
+// Parse scans and parses source. It returns the lexical and parse errors
+// as an ErrorList, in which case the statements should not be run.
+func Parse(source string) ([]Stmt, error) {
+	return ParseFile("", source)
+}
+
+// ParseFile is Parse for the source of the named file, which the spans of
+// tokens, nodes and errors record.
+func ParseFile(file string, source string) ([]Stmt, error) {
+	return parse(file, source, DialectExtended)
+}
+
+func parse(file string, source string, dialect Dialect) ([]Stmt, error) {
+	scanner := NewFileScanner(file, source)
+	scanner.SetDialect(dialect)
+	tokens := scanner.ScanTokens()
+	parser := NewParser(tokens)
+	parser.SetDialect(dialect)
+	statements := parser.Parse()
+	errs := append(ErrorList{}, scanner.Errors()...)
+	errs = append(errs, parser.Errors()...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	resolver := NewResolver()
+	resolver.SetDialect(dialect)
+	resolver.Resolve(statements)
+	if errs := resolver.Errors(); len(errs) > 0 {
+		return nil, ErrorList(errs)
+	}
+	return statements, nil
+}
+
+// Eval runs source in a new interpreter. See Interpreter.Eval.
+func Eval(source string) (Value, error) {
+	return NewInterpreter().Eval(source)
+}
+
+// Eval runs source and returns the value of its last statement when that
+// is an expression statement. Globals persist between calls.
+func (i *Interpreter) Eval(source string) (Value, error) {
+	return i.Run(i.limits.ctx, source)
+}
+
+// Run is Eval that stops the script with a LimitError once ctx is done.
+// Lexical and parse errors keep the source from running at all. Runtime
+// and limit errors raised by the program, by the tasks it spawns and by
+// event loop callbacks are collected instead of printed, and returned as
+// an ErrorList.
+func (i *Interpreter) Run(ctx context.Context, source string) (Value, error) {
+	statements, err := i.Parse("", source)
+	if err != nil {
+		return nil, err
+	}
+	i.reporter.start(true)
+	value := i.run(ctx, statements)
+	if errs := i.reporter.stop(); len(errs) > 0 {
+		return value, errs
+	}
+	return value, nil
+}
+
+// reporter prints runtime errors to w and records them for the caller of
+// the run. Run records them without printing. Spawned tasks report from
+// their own goroutines.
+type reporter struct {
+	w        io.Writer
+	renderer *Renderer
+	mu       sync.Mutex
+	quiet    bool
+	errors   ErrorList
+}
+
+func (r *reporter) report(err error) {
+	if r == nil {
+		fmt.Fprint(os.Stderr, ErrorList{err})
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, err)
+	switch {
+	case r.quiet:
+	case r.renderer != nil:
+		r.renderer.Render(r.w, Diagnose(err))
+	default:
+		fmt.Fprint(r.w, ErrorList{err})
+	}
+}
+
+// start begins recording the errors of a run.
+func (r *reporter) start(quiet bool) {
+	r.mu.Lock()
+	r.quiet = quiet
+	r.errors = nil
+	r.mu.Unlock()
+}
+
+// stop returns the errors recorded since start.
+func (r *reporter) stop() ErrorList {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quiet = false
+	errs := r.errors
+	r.errors = nil
+	return errs
+}
+
+// Call calls the global function name with Go arguments, converted as the
+// results of functions registered with RegisterFunc are. Lists in the
+// result become []interface{}, and an async function's promise is awaited
+// by running the event loop. Errors are returned as for Run.
+func (i *Interpreter) Call(name string, args ...interface{}) (Value, error) {
+	i.GlobalEnv.mu.RLock()
+	callee, ok := i.GlobalEnv.Values[name]
+	i.GlobalEnv.mu.RUnlock()
+	if !ok {
+		return nil, ErrorList{&RuntimeError{Msg: fmt.Sprintf("Undefined variable '%s'.", name)}}
+	}
+	f, ok := callee.(Callable)
+	if !ok {
+		return nil, ErrorList{&RuntimeError{Msg: "Can only call functions and classes."}}
+	}
+	if !arityMatches(f, len(args)) {
+		return nil, ErrorList{&RuntimeError{Msg: fmt.Sprintf("%s expects %d arguments but got %d.", name, f.Arity(), len(args))}}
+	}
+	values := make([]interface{}, len(args))
+	for idx, arg := range args {
+		values[idx] = fromGo(reflect.ValueOf(arg))
+	}
+
+	i.reporter.start(true)
+	result := i.call(f, values)
+	if p, ok := result.(*Promise); ok {
+		switch p.state {
+		case promiseFulfilled:
+			result = p.value
+		case promiseRejected:
+			i.reporter.report(p.err)
+			result = nil
+		}
+	}
+	if errs := i.reporter.stop(); len(errs) > 0 {
+		return toHost(result), errs
+	}
+	return toHost(result), nil
+}
+
+// call calls f as the main task, then runs the event loop.
+func (i *Interpreter) call(f Callable, args []interface{}) (result interface{}) {
+	i.begin(i.limits.ctx)
+	i.tasks.enter(i.task)
+	defer i.tasks.leave(i.task)
+	defer func() {
+		if r := recover(); r != nil {
+			switch e := r.(type) {
+			case *RuntimeError:
+				i.reporter.report(e)
+			case *DeadlockError:
+				i.reporter.report(e)
+			case *LimitError:
+				i.reporter.report(e)
+			default:
+				panic(r)
+			}
+		}
+	}()
+	result = f.Call(i, args)
+	if p, ok := result.(*Promise); ok {
+		p.handled = true
+	}
+	i.loop.Run()
+	return result
+}
+
+// toHost converts lists in a Lox value to slices.
+func toHost(value interface{}) interface{} {
+	l, ok := value.(*List)
+	if !ok {
+		return value
+	}
+	elements := make([]interface{}, len(l.Elements))
+	for idx, element := range l.Elements {
+		elements[idx] = toHost(element)
+	}
+	return elements
+}
@@ -0,0 +1,95 @@
+package lox
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestEval ...
+func TestEval(t *testing.T) {
+	i := NewInterpreter()
+	if _, err := i.Eval("fun double(n) { return n * 2; }"); err != nil {
+		t.Fatal(err)
+	}
+	value, err := i.Eval("var x = 20; double(x) + 2;")
+	if err != nil || value != 42.0 {
+		t.Errorf("got %v, %v, expected 42", value, err)
+	}
+	if value, _ := Eval("var y = 1;"); value != nil {
+		t.Errorf("got %v, expected nil after a declaration", value)
+	}
+}
+
+// TestEvalErrors ...
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		check  func(error) bool
+	}{
+		{`var s = "open;`, func(e error) bool { _, ok := e.(*LexError); return ok }},
+		{"print 1 +;", func(e error) bool { _, ok := e.(*ParseError); return ok }},
+		{"close(1);", func(e error) bool { re, ok := e.(*RuntimeError); return ok && re.Line == 1 }},
+		{"recv(chan(0));", func(e error) bool { _, ok := e.(*DeadlockError); return ok }},
+	}
+	for _, test := range tests {
+		_, err := Eval(test.source)
+		errs, ok := err.(ErrorList)
+		if !ok || len(errs) == 0 || !test.check(errs[0]) {
+			t.Errorf("%s: got %#v", test.source, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewInterpreter().Run(ctx, "print 1;")
+	if errs, ok := err.(ErrorList); !ok || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("got %v, expected context.Canceled", err)
+	}
+}
+
+// TestCall ...
+func TestCall(t *testing.T) {
+	i := NewInterpreter()
+	_, err := i.Eval(`
+var events = 0;
+fun onEvent(name, count) {
+  events = events + count;
+  return list(0..count);
+}
+async fun later(x) {
+  await sleep(1);
+  return x + 1;
+}
+async fun fails() {
+  close(1);
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 3; n++ {
+		if _, err := i.Call("onEvent", "tick", 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := i.Call("onEvent", "tick", uint8(3))
+	if got, ok := result.([]interface{}); err != nil || !ok || len(got) != 3 || got[2] != 2.0 {
+		t.Errorf("got %#v, %v, expected [0 1 2]", result, err)
+	}
+	if got := i.GlobalEnv.Get("events"); got != 9.0 {
+		t.Errorf("got %v events, expected 9", got)
+	}
+	if result, err := i.Call("later", 41); err != nil || result != 42.0 {
+		t.Errorf("got %v, %v, expected 42", result, err)
+	}
+	if _, err := i.Call("fails"); err == nil || len(err.(ErrorList)) != 1 {
+		t.Errorf("got %v, expected one rejection", err)
+	}
+	if _, err := i.Call("missing"); err == nil {
+		t.Error("expected calling an undefined function to fail")
+	}
+	if _, err := i.Call("onEvent", "tick"); err == nil {
+		t.Error("expected an arity error")
+	}
+}
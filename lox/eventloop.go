@@ -0,0 +1,279 @@
+package lox
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// EventLoop schedules promise callbacks and timers for the main task. It is
+// single-threaded: callbacks run one at a time on the goroutine that drives
+// the loop, which is the one running Interpret.
+type EventLoop struct {
+	microtasks []func()
+	timers     timerQueue
+	byID       map[int]*timer
+	nextID     int
+	seq        int
+	rejections []*Promise
+	reporter   *reporter
+	// ctx stops the loop, with a LimitError, once done.
+	ctx context.Context
+}
+
+type timer struct {
+	id       int
+	seq      int
+	when     time.Time
+	interval time.Duration
+	callback func()
+	stopped  bool
+}
+
+// NewEventLoop ...
+func NewEventLoop() *EventLoop {
+	return &EventLoop{byID: make(map[int]*timer)}
+}
+
+func (l *EventLoop) enqueue(microtask func()) {
+	l.microtasks = append(l.microtasks, microtask)
+}
+
+// schedule registers callback to run after delay, and then every interval
+// if interval is positive. It returns the id of the timer.
+func (l *EventLoop) schedule(delay time.Duration, interval time.Duration, callback func()) int {
+	l.nextID++
+	l.seq++
+	t := &timer{id: l.nextID, seq: l.seq, when: time.Now().Add(delay), interval: interval, callback: callback}
+	l.byID[t.id] = t
+	heap.Push(&l.timers, t)
+	return t.id
+}
+
+func (l *EventLoop) cancel(id int) {
+	if t, ok := l.byID[id]; ok {
+		t.stopped = true
+		delete(l.byID, id)
+	}
+}
+
+// Run processes microtasks and timers until none are left.
+func (l *EventLoop) Run() {
+	l.runUntil(func() bool { return false })
+}
+
+// runUntil processes events until done reports true or the loop runs out
+// of work.
+func (l *EventLoop) runUntil(done func() bool) {
+	for {
+		l.runMicrotasks()
+		l.reportRejections()
+		if done() || len(l.timers) == 0 {
+			return
+		}
+		t := heap.Pop(&l.timers).(*timer)
+		if t.stopped {
+			continue
+		}
+		if wait := time.Until(t.when); wait > 0 {
+			l.sleep(wait)
+		}
+		if t.interval > 0 {
+			l.seq++
+			t.seq = l.seq
+			t.when = t.when.Add(t.interval)
+			heap.Push(&l.timers, t)
+		} else {
+			delete(l.byID, t.id)
+		}
+		l.runTask(t.callback)
+	}
+}
+
+// sleep waits for the next timer, unless the context is done first.
+func (l *EventLoop) sleep(wait time.Duration) {
+	if l.ctx == nil || l.ctx.Done() == nil {
+		time.Sleep(wait)
+		return
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-l.ctx.Done():
+		l.reset()
+		panic(&LimitError{Err: l.ctx.Err()})
+	}
+}
+
+// reset drops the pending callbacks and timers when a LimitError stops the
+// program.
+func (l *EventLoop) reset() {
+	l.microtasks = nil
+	l.timers = nil
+	l.byID = make(map[int]*timer)
+	l.rejections = nil
+}
+
+func (l *EventLoop) runMicrotasks() {
+	for len(l.microtasks) > 0 {
+		microtask := l.microtasks[0]
+		l.microtasks = l.microtasks[1:]
+		l.runTask(microtask)
+	}
+}
+
+// runTask runs a callback, reporting a runtime error it raises rather than
+// stopping the loop.
+func (l *EventLoop) runTask(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*LimitError); ok {
+				l.reset()
+			}
+			re, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			l.reporter.report(re)
+		}
+	}()
+	task()
+}
+
+func (l *EventLoop) reportRejections() {
+	for _, p := range l.rejections {
+		if !p.handled {
+			l.reporter.report(&RuntimeError{Line: p.err.Line, Token: p.err.Token, Msg: "Unhandled promise rejection: " + p.err.Msg, Trace: p.err.Trace})
+		}
+	}
+	l.rejections = nil
+}
+
+// timerQueue is a min-heap of timers ordered by deadline, then by creation.
+type timerQueue []*timer
+
+func (q timerQueue) Len() int { return len(q) }
+
+func (q timerQueue) Less(a, b int) bool {
+	if q[a].when.Equal(q[b].when) {
+		return q[a].seq < q[b].seq
+	}
+	return q[a].when.Before(q[b].when)
+}
+
+func (q timerQueue) Swap(a, b int) { q[a], q[b] = q[b], q[a] }
+
+func (q *timerQueue) Push(x interface{}) { *q = append(*q, x.(*timer)) }
+
+func (q *timerQueue) Pop() interface{} {
+	old := *q
+	t := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return t
+}
+
+// mainLoop returns the event loop, which belongs to the main task.
+func (i *Interpreter) mainLoop() *EventLoop {
+	if i.task != i.tasks.main {
+		panic(&RuntimeError{Msg: "Promises and timers can only be used from the main task."})
+	}
+	return i.loop
+}
+
+func toMilliseconds(native string, value interface{}) time.Duration {
+	ms, ok := value.(float64)
+	if !ok || ms < 0 {
+		panic(&RuntimeError{Msg: fmt.Sprintf("%s() expects a non-negative number of milliseconds.", native)})
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// timerCallback wraps a Lox callable so the loop can call it as the main
+// task, outside of any generator or async body.
+func timerCallback(native string, i *Interpreter, value interface{}) func() {
+	f, ok := value.(Callable)
+	if !ok || f.Arity() != 0 {
+		panic(&RuntimeError{Msg: fmt.Sprintf("%s() expects a function without parameters, not %v.", native, reflect.TypeOf(value))})
+	}
+	ci := *i
+	ci.co = nil
+	ci.async = nil
+	ci.depth = 0
+	ci.frames = nil
+	return func() {
+		f.Call(&ci, nil)
+	}
+}
+
+// Sleep ...
+type Sleep struct{}
+
+// Arity ...
+func (s Sleep) Arity() int {
+	return 1
+}
+
+// Call ...
+func (s Sleep) Call(i *Interpreter, args []interface{}) interface{} {
+	loop := i.mainLoop()
+	delay := toMilliseconds("sleep", args[0])
+	p := NewPromise(loop)
+	loop.schedule(delay, 0, func() {
+		p.resolve(nil)
+	})
+	return p
+}
+
+// SetTimeout ...
+type SetTimeout struct{}
+
+// Arity ...
+func (st SetTimeout) Arity() int {
+	return 2
+}
+
+// Call ...
+func (st SetTimeout) Call(i *Interpreter, args []interface{}) interface{} {
+	loop := i.mainLoop()
+	callback := timerCallback("setTimeout", i, args[0])
+	return float64(loop.schedule(toMilliseconds("setTimeout", args[1]), 0, callback))
+}
+
+// SetInterval ...
+type SetInterval struct{}
+
+// Arity ...
+func (si SetInterval) Arity() int {
+	return 2
+}
+
+// Call ...
+func (si SetInterval) Call(i *Interpreter, args []interface{}) interface{} {
+	loop := i.mainLoop()
+	callback := timerCallback("setInterval", i, args[0])
+	interval := toMilliseconds("setInterval", args[1])
+	if interval == 0 {
+		panic(&RuntimeError{Msg: "setInterval() expects a positive interval."})
+	}
+	return float64(loop.schedule(interval, interval, callback))
+}
+
+// ClearTimer ...
+type ClearTimer struct{}
+
+// Arity ...
+func (ct ClearTimer) Arity() int {
+	return 1
+}
+
+// Call ...
+func (ct ClearTimer) Call(i *Interpreter, args []interface{}) interface{} {
+	loop := i.mainLoop()
+	if id, ok := args[0].(float64); ok {
+		loop.cancel(int(id))
+	}
+	return nil
+}
@@ -3,16 +3,20 @@ package lox
 // Expr ...
 type Expr interface {
 	Accept(v ExprVisitor) interface{}
+	Span() Span
 }
 
 // ExprAssign ...
 type ExprAssign struct {
-	Name  Token
-	Value Expr
+	Node
+	Name    Token
+	Value   Expr
+	Binding *Binding
 }
 
 // ExprBinary ...
 type ExprBinary struct {
+	Node
 	Left     Expr
 	Operator Token
 	Right    Expr
@@ -20,27 +24,47 @@ type ExprBinary struct {
 
 // ExprGrouping ...
 type ExprGrouping struct {
+	Node
 	Expr
 }
 
+// Span is the span of the parenthesized expression, parentheses included,
+// rather than that of the inner expression.
+func (e *ExprGrouping) Span() Span {
+	return e.Source
+}
+
 // ExprLiteral ...
 type ExprLiteral struct {
+	Node
 	Value interface{}
 }
 
 // ExprUnary ...
 type ExprUnary struct {
+	Node
 	Operator Token
 	Right    Expr
 }
 
 // ExprVar ...
 type ExprVar struct {
-	Name Token
+	Node
+	Name    Token
+	Binding *Binding
+}
+
+// Binding is where the Resolver found a local variable: Depth environments
+// up from the current one, at index Slot. Variables without a binding are
+// globals.
+type Binding struct {
+	Depth int
+	Slot  int
 }
 
 // ExprLogical ...
 type ExprLogical struct {
+	Node
 	Left     Expr
 	Operator Token
 	Right    Expr
@@ -48,11 +72,34 @@ type ExprLogical struct {
 
 // ExprCall ..
 type ExprCall struct {
+	Node
 	Callee    Expr
 	Paren     Token
 	Arguments []*Expr
 }
 
+// ExprAwait ...
+type ExprAwait struct {
+	Node
+	Keyword Token
+	Value   Expr
+}
+
+// ExprGet ...
+type ExprGet struct {
+	Node
+	Object Expr
+	Name   Token
+}
+
+// ExprSet ...
+type ExprSet struct {
+	Node
+	Object Expr
+	Name   Token
+	Value  Expr
+}
+
 // Accept ...
 func (e *ExprAssign) Accept(v ExprVisitor) interface{} { return v.VisitAssignExpr(e) }
 
@@ -76,3 +123,12 @@ func (e *ExprLogical) Accept(v ExprVisitor) interface{} { return v.VisitLogicalE
 
 // Accept ...
 func (e *ExprCall) Accept(v ExprVisitor) interface{} { return v.VisitCallExpr(e) }
+
+// Accept ...
+func (e *ExprAwait) Accept(v ExprVisitor) interface{} { return v.VisitAwaitExpr(e) }
+
+// Accept ...
+func (e *ExprGet) Accept(v ExprVisitor) interface{} { return v.VisitGetExpr(e) }
+
+// Accept ...
+func (e *ExprSet) Accept(v ExprVisitor) interface{} { return v.VisitSetExpr(e) }
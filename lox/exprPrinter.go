@@ -19,6 +19,10 @@ func PrintExpr(e Expr) string {
 		return parenthesize("group", t.Expr)
 	case *ExprUnary:
 		return parenthesize(t.Operator.Lexeme, t.Right)
+	case *ExprGet:
+		return parenthesize("."+t.Name.Lexeme, t.Object)
+	case *ExprSet:
+		return parenthesize("="+t.Name.Lexeme, t.Object, t.Value)
 	default:
 		return ""
 	}
@@ -8,12 +8,12 @@ import (
 func TestExpressionPrinter(t *testing.T) {
 	expr := ExprBinary{
 		Left: &ExprUnary{
-			Operator: lox.Token{lox.MINUS, "-", nil, 1},
-			Right:    &ExprLiteral{123},
+			Operator: Token{Type: TokenTypeMinus, Lexeme: "-", Line: 1},
+			Right:    &ExprLiteral{Value: 123},
 		},
-		Operator: lox.Token{lox.STAR, "*", nil, 1},
+		Operator: Token{Type: TokenTypeStar, Lexeme: "*", Line: 1},
 		Right: &ExprGrouping{
-			Expr: &ExprLiteral{45.67},
+			Expr: &ExprLiteral{Value: 45.67},
 		},
 	}
 	expected := "(* (- 123) (group 45.67))"
@@ -10,4 +10,7 @@ type ExprVisitor interface {
 	VisitVarExpr(ev *ExprVar) interface{}
 	VisitLogicalExpr(eb *ExprLogical) interface{}
 	VisitCallExpr(ec *ExprCall) interface{}
+	VisitAwaitExpr(ea *ExprAwait) interface{}
+	VisitGetExpr(eg *ExprGet) interface{}
+	VisitSetExpr(es *ExprSet) interface{}
 }
@@ -3,20 +3,35 @@ package lox
 // Function ...
 type Function struct {
 	Declaration FunctionStmt
+	Closure     *Environment
 }
 
 // NewFunction ...
-func NewFunction(declaration FunctionStmt) *Function {
-	return &Function{Declaration: declaration}
+func NewFunction(declaration FunctionStmt, closure *Environment) *Function {
+	closure.share()
+	return &Function{Declaration: declaration, Closure: closure}
 }
 
 // Call ...
 func (f Function) Call(i *Interpreter, args []interface{}) interface{} {
-	env := NewEnvironment(i.GlobalEnv)
-	for idx, param := range f.Declaration.Params {
-		env.Define(param.Lexeme, args[idx])
+	env := NewScope(f.Closure, f.Declaration.Slots)
+	copy(env.Slots, args)
+	if f.Declaration.IsGenerator {
+		return NewGenerator(i, f.Declaration, env)
+	}
+	if f.Declaration.IsAsync {
+		return NewAsyncCall(i, f.Declaration, env)
+	}
+	// An await in a plain function called from an async one waits for the
+	// promise instead of suspending the caller.
+	if i.async != nil {
+		fi := *i
+		fi.async = nil
+		i = &fi
+	}
+	if rv, ok := i.ExecuteBlock(f.Declaration.Body, env).(*ReturnValue); ok {
+		return rv.Value
 	}
-	i.ExecuteBlock(f.Declaration.Body, env)
 	return nil
 }
 
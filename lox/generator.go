@@ -0,0 +1,95 @@
+package lox
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Generator is the iterator returned by calling a function whose body
+// contains yield. The body runs lazily, one yield at a time.
+type Generator struct {
+	*generatorState
+}
+
+// generatorState is kept apart from Generator so the running body never
+// references the handle, which lets the finalizer cancel abandoned
+// generators.
+type generatorState struct {
+	name     string
+	co       *coroutine
+	buffered bool
+	value    interface{}
+}
+
+// NewGenerator ...
+func NewGenerator(i *Interpreter, declaration FunctionStmt, env *Environment) *Generator {
+	gi := *i
+	co := newCoroutine(func(co *coroutine) interface{} {
+		gi.co = co
+		gi.async = nil
+		gi.ExecuteBlock(declaration.Body, env)
+		return nil
+	})
+	g := &Generator{&generatorState{name: declaration.Name.Lexeme, co: co}}
+	runtime.SetFinalizer(g, func(g *Generator) {
+		g.Close()
+	})
+	return g
+}
+
+// HasNext runs the body up to its next yield, if it has not done so yet.
+func (g *Generator) HasNext() bool {
+	if !g.buffered {
+		g.value, g.buffered = g.co.resume(nil)
+		if !g.buffered {
+			g.value = nil
+		}
+	}
+	return g.buffered
+}
+
+// Next ...
+func (g *Generator) Next() interface{} {
+	if !g.HasNext() {
+		return nil
+	}
+	g.buffered = false
+	return g.value
+}
+
+// Close abandons the generator and stops its body.
+func (g *Generator) Close() {
+	g.co.cancel()
+}
+
+// String ...
+func (g *Generator) String() string {
+	return fmt.Sprintf("<generator %s>", g.name)
+}
+
+func findYield(statements []Stmt) *YieldStmt {
+	for _, statement := range statements {
+		var found *YieldStmt
+		switch stmt := statement.(type) {
+		case *YieldStmt:
+			found = stmt
+		case *BlockStmt:
+			found = findYield(stmt.Statements)
+		case *IfStmt:
+			found = findYield([]Stmt{stmt.ThenBranch, stmt.ElseBranch})
+		case *WhileStmt:
+			found = findYield([]Stmt{stmt.Body})
+		case *SelectStmt:
+			found = findYield(stmt.Default)
+			for _, c := range stmt.Cases {
+				if found == nil {
+					found = findYield(c.Body)
+				}
+			}
+		}
+		if found != nil {
+			return found
+		}
+	}
+	return nil
+}
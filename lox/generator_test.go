@@ -0,0 +1,70 @@
+package lox
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// mustParse parses and resolves source, which the tests expect to be
+// valid.
+func mustParse(source string) []Stmt {
+	stmts, err := Parse(source)
+	if err != nil {
+		panic(err)
+	}
+	return stmts
+}
+
+func interpret(source string) *Interpreter {
+	i := NewInterpreter()
+	i.Interpret(mustParse(source))
+	return i
+}
+
+// generatorProgram takes the first naturals from an endless generator.
+const generatorProgram = `
+fun naturals() {
+  var n = 0;
+  while (true) {
+    yield n;
+    n = n + 1;
+  }
+}
+fun take(g, n) {
+  while (n > 0) {
+    if (hasNext(g)) yield next(g); else return;
+    n = n - 1;
+  }
+}
+var taken = list(take(naturals(), 4));
+`
+
+// TestGenerator ...
+func TestGenerator(t *testing.T) {
+	i := interpret(generatorProgram)
+	got := i.GlobalEnv.Get("taken").(*List).String()
+	if got != "[0, 1, 2, 3]" {
+		t.Errorf("got %s, expected [0, 1, 2, 3]", got)
+	}
+}
+
+// TestGeneratorClose ...
+func TestGeneratorClose(t *testing.T) {
+	i := interpret(`fun forever() { while (true) yield 1; }`)
+	before := runtime.NumGoroutine()
+	g := i.GlobalEnv.Get("forever").(Callable).Call(i, nil).(*Generator)
+	if !g.HasNext() || g.Next() != 1.0 {
+		t.Fatal("expected the generator to yield 1")
+	}
+	g.Close()
+	if g.HasNext() {
+		t.Error("expected a closed generator to be exhausted")
+	}
+	for attempt := 0; runtime.NumGoroutine() > before; attempt++ {
+		if attempt == 100 {
+			t.Fatalf("generator goroutine still running after Close")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
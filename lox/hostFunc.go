@@ -0,0 +1,217 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// VariadicArity is the arity of callables that take any number of
+// arguments and check them themselves.
+const VariadicArity = -1
+
+func arityMatches(f Callable, argCount int) bool {
+	return f.Arity() == VariadicArity || f.Arity() == argCount
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterFunc exposes a Go function to scripts as the global name.
+//
+// Arguments are converted to the parameter types of fn: numbers to any
+// numeric type (integers must be whole and in range), strings, booleans,
+// lists to slices, and nil to pointers, slices, maps and interfaces. Other
+// values are passed through when assignable. fn may return nothing, a
+// value, an error, or a value and an error; a non-nil error is raised as a
+// runtime error. Results are converted back the same way, with every
+// numeric type becoming a number. Variadic functions accept any number of
+// trailing arguments.
+func (i *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return fmt.Errorf("RegisterFunc(%q): expected a function, not %T", name, fn)
+	}
+	typ := value.Type()
+	switch typ.NumOut() {
+	case 0, 1:
+	case 2:
+		if typ.Out(1) != errorType {
+			return fmt.Errorf("RegisterFunc(%q): the second result of %v must be an error", name, typ)
+		}
+	default:
+		return fmt.Errorf("RegisterFunc(%q): %v has more than two results", name, typ)
+	}
+	i.GlobalEnv.Define(name, &HostFunc{name: name, fn: value})
+	return nil
+}
+
+// HostFunc is a Go function registered with RegisterFunc.
+type HostFunc struct {
+	name string
+	fn   reflect.Value
+}
+
+// Arity ...
+func (h *HostFunc) Arity() int {
+	if h.fn.Type().IsVariadic() {
+		return VariadicArity
+	}
+	return h.fn.Type().NumIn()
+}
+
+// Call ...
+func (h *HostFunc) Call(i *Interpreter, args []interface{}) interface{} {
+	typ := h.fn.Type()
+	params := typ.NumIn()
+	if typ.IsVariadic() && len(args) < params-1 {
+		panic(&RuntimeError{Msg: fmt.Sprintf("%s() expects at least %d arguments but got %d.", h.name, params-1, len(args))})
+	}
+
+	in := make([]reflect.Value, len(args))
+	for idx, arg := range args {
+		var target reflect.Type
+		if typ.IsVariadic() && idx >= params-1 {
+			target = typ.In(params - 1).Elem()
+		} else {
+			target = typ.In(idx)
+		}
+		value, err := toGo(arg, target)
+		if err != nil {
+			panic(&RuntimeError{Msg: fmt.Sprintf("%s() argument %d: %v.", h.name, idx+1, err)})
+		}
+		in[idx] = value
+	}
+
+	out := h.call(in)
+	if len(out) > 0 && typ.Out(len(out)-1) == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			panic(&RuntimeError{Msg: err.Error()})
+		}
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return fromGo(out[0])
+}
+
+// call calls the Go function, raising a Go panic in it as a runtime error
+// of the script. Errors of the interpreter, from Lox functions the Go
+// function called, pass through.
+func (h *HostFunc) call(in []reflect.Value) []reflect.Value {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r.(type) {
+			case *RuntimeError, *LimitError, *DeadlockError, coroutineCancelled, taskAborted:
+				panic(r)
+			}
+			panic(&RuntimeError{Msg: fmt.Sprintf("%s() panicked: %v.", h.name, r)})
+		}
+	}()
+	return h.fn.Call(in)
+}
+
+// String ...
+func (h *HostFunc) String() string {
+	return fmt.Sprintf("<native fn %s>", h.name)
+}
+
+// toGo converts a Lox value to the Go type t.
+func toGo(value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected %v, not nil", t)
+	}
+
+	v := reflect.ValueOf(value)
+	switch n := value.(type) {
+	case float64:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			r := reflect.New(t).Elem()
+			if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 || r.OverflowInt(int64(n)) {
+				return reflect.Value{}, fmt.Errorf("%v does not fit in %v", n, t)
+			}
+			r.SetInt(int64(n))
+			return r, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			r := reflect.New(t).Elem()
+			if n != math.Trunc(n) || n < 0 || n >= math.MaxUint64 || r.OverflowUint(uint64(n)) {
+				return reflect.Value{}, fmt.Errorf("%v does not fit in %v", n, t)
+			}
+			r.SetUint(uint64(n))
+			return r, nil
+		case reflect.Float32, reflect.Float64:
+			return v.Convert(t), nil
+		}
+	case string:
+		if t.Kind() == reflect.String {
+			return v.Convert(t), nil
+		}
+	case bool:
+		if t.Kind() == reflect.Bool {
+			return v.Convert(t), nil
+		}
+	case *List:
+		if t.Kind() == reflect.Slice {
+			r := reflect.MakeSlice(t, len(n.Elements), len(n.Elements))
+			for idx, element := range n.Elements {
+				converted, err := toGo(element, t.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("element %d: %v", idx, err)
+				}
+				r.Index(idx).Set(converted)
+			}
+			return r, nil
+		}
+	}
+	if h, ok := value.(*HostObject); ok && h.v.Type().AssignableTo(t) {
+		v = h.v
+	}
+	if v.Type().AssignableTo(t) {
+		r := reflect.New(t).Elem()
+		r.Set(v)
+		return r, nil
+	}
+	return reflect.Value{}, fmt.Errorf("expected %v, not %v", t, v.Type())
+}
+
+// fromGo converts a Go value to a Lox value.
+func fromGo(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return fromGo(v.Elem())
+	case reflect.Ptr, reflect.Map, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			return nil
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		elements := make([]interface{}, v.Len())
+		for idx := range elements {
+			elements[idx] = fromGo(v.Index(idx))
+		}
+		return NewList(elements)
+	}
+	return v.Interface()
+}
@@ -0,0 +1,93 @@
+package lox
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestRegisterFunc ...
+func TestRegisterFunc(t *testing.T) {
+	i := NewInterpreter()
+	funcs := map[string]interface{}{
+		"repeat": strings.Repeat,
+		"sum": func(base int, values ...int) int {
+			for _, v := range values {
+				base += v
+			}
+			return base
+		},
+		"split": strings.Split,
+		"join":  func(parts []string) string { return strings.Join(parts, "+") },
+		"half": func(n int) (int, error) {
+			if n%2 != 0 {
+				return 0, errors.New("odd number")
+			}
+			return n / 2, nil
+		},
+	}
+	for name, fn := range funcs {
+		if err := i.RegisterFunc(name, fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	i.Interpret(mustParse(`
+var repeated = repeat("ab", 3);
+var none = sum(1);
+var total = sum(1, 2, 3);
+var joined = join(split("a,b,c", ","));
+var halved = half(8);
+var failed = "unchanged";
+failed = half(3);
+`))
+
+	expected := map[string]interface{}{
+		"repeated": "ababab",
+		"none":     1.0,
+		"total":    6.0,
+		"joined":   "a+b+c",
+		"halved":   4.0,
+		"failed":   "unchanged",
+	}
+	for name, want := range expected {
+		if got := i.GlobalEnv.Get(name); got != want {
+			t.Errorf("%s: got %v, expected %v", name, got, want)
+		}
+	}
+}
+
+// TestRegisterFuncConversionError ...
+func TestRegisterFuncConversionError(t *testing.T) {
+	i := NewInterpreter()
+	i.RegisterFunc("byte", func(b uint8) uint8 { return b })
+	for _, arg := range []interface{}{256.0, 1.5, "x"} {
+		func() {
+			defer func() {
+				re, ok := recover().(*RuntimeError)
+				if !ok || !strings.Contains(re.Msg, "byte() argument 1") {
+					t.Errorf("byte(%v): expected a conversion error, got %v", arg, re)
+				}
+			}()
+			i.GlobalEnv.Get("byte").(Callable).Call(i, []interface{}{arg})
+		}()
+	}
+	if err := i.RegisterFunc("bad", 42); err == nil {
+		t.Error("expected registering a non-function to fail")
+	}
+}
+
+// TestRegisterFuncPanic checks that a panic in a Go function is raised as
+// a runtime error naming the function.
+func TestRegisterFuncPanic(t *testing.T) {
+	i := NewInterpreter(WithStderr(&bytes.Buffer{}))
+	i.RegisterFunc("idx", func(xs []int, n int) int { return xs[n] })
+	stmts, err := Parse("idx(list(1..3), 5);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := i.Interpret(stmts)
+	if len(ds) != 1 || ds[0].Phase != PhaseRuntime || ds[0].Span.Line != 1 || !strings.HasPrefix(ds[0].Message, "idx() panicked: ") {
+		t.Errorf("got %v, expected a runtime error from idx()", ds)
+	}
+}
@@ -1,7 +1,11 @@
 package lox
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"reflect"
 )
 
@@ -9,34 +13,123 @@ import (
 type Interpreter struct {
 	Env       *Environment
 	GlobalEnv *Environment
+
+	// co is the coroutine of the generator whose body is being executed,
+	// nil outside generators.
+	co *coroutine
+
+	// Every copy of the interpreter runs on behalf of one task. Copies are
+	// never shared between goroutines; tasks share state only through
+	// environments and channels, which are safe for concurrent use.
+	tasks *scheduler
+	task  *task
+
+	// loop runs promise callbacks and timers once the top-level statements
+	// are done; async is the coroutine of the async body being executed.
+	loop  *EventLoop
+	async *coroutine
+
+	// reporter receives the runtime errors that end a task or a callback.
+	reporter *reporter
+
+	// Program output goes to stdout and diagnostics to stderr.
+	stdout io.Writer
+	stderr io.Writer
+	stdin  *lineReader
+	// renderer, if set, formats the errors written to stderr.
+	renderer *Renderer
+
+	// limits stop runaway scripts; depth is the number of calls in
+	// progress in this task.
+	limits *limits
+	depth  int
+	// frames are the calls in progress, for tracebacks. tracer is the
+	// engine calling a native, if that is the VM or the closure compiler.
+	frames *frame
+	tracer tracer
+
+	dialect Dialect
 }
 
+// Option configures an Interpreter.
+type Option func(*Interpreter)
+
 // NewInterpreter ...
-func NewInterpreter() *Interpreter {
+func NewInterpreter(opts ...Option) *Interpreter {
 	ni := new(Interpreter)
+	ni.stdout = newSyncWriter(os.Stdout)
+	ni.stderr = newSyncWriter(os.Stderr)
+	ni.stdin = &lineReader{r: bufio.NewReader(os.Stdin)}
+	ni.limits = &limits{ctx: context.Background(), maxRecursion: DefaultMaxRecursion}
+	for _, opt := range opts {
+		opt(ni)
+	}
+	ni.reporter = &reporter{w: ni.stderr, renderer: ni.renderer}
 	ni.Env = NewEnvironment(nil)
 	ni.GlobalEnv = ni.Env
+	ni.tasks = newScheduler()
+	ni.task = ni.tasks.main
+	ni.loop = NewEventLoop()
+	ni.loop.reporter = ni.reporter
 	ni.GlobalEnv.Define("clock", &Clock{})
+	ni.GlobalEnv.Define("step", &Step{})
+	ni.GlobalEnv.Define("iter", &Iter{})
+	ni.GlobalEnv.Define("hasNext", &HasNext{})
+	ni.GlobalEnv.Define("next", &Next{})
+	ni.GlobalEnv.Define("list", &ToList{})
+	ni.GlobalEnv.Define("contains", &Contains{})
+	ni.GlobalEnv.Define("chan", &Chan{})
+	ni.GlobalEnv.Define("send", &Send{})
+	ni.GlobalEnv.Define("recv", &Recv{})
+	ni.GlobalEnv.Define("close", &Close{})
+	ni.GlobalEnv.Define("sleep", &Sleep{})
+	ni.GlobalEnv.Define("setTimeout", &SetTimeout{})
+	ni.GlobalEnv.Define("setInterval", &SetInterval{})
+	ni.GlobalEnv.Define("clearTimeout", &ClearTimer{})
+	ni.GlobalEnv.Define("clearInterval", &ClearTimer{})
+	ni.GlobalEnv.Define("readLine", &ReadLine{})
+	ni.GlobalEnv.Define("stacktrace", &StackTrace{})
+	ni.GlobalEnv.Define("str", &Str{})
 	return ni
 }
 
 // VisitLiteralExpr ...
-func (i Interpreter) VisitLiteralExpr(expr *ExprLiteral) interface{} {
+func (i *Interpreter) VisitLiteralExpr(expr *ExprLiteral) interface{} {
 	return expr.Value
 }
 
 // VisitGroupingExpr ...
-func (i Interpreter) VisitGroupingExpr(expr *ExprGrouping) interface{} {
+func (i *Interpreter) VisitGroupingExpr(expr *ExprGrouping) interface{} {
 	return i.evaluate(expr.Expr)
 }
 
 // VisitVarExpr ...
-func (i Interpreter) VisitVarExpr(expr *ExprVar) interface{} {
-	return i.Env.Get(expr.Name.Lexeme)
+func (i *Interpreter) VisitVarExpr(expr *ExprVar) interface{} {
+	if b := expr.Binding; b != nil {
+		return i.Env.GetAt(b.Depth, b.Slot)
+	}
+	return i.getGlobal(expr.Name)
+}
+
+// getGlobal reads the global variable name, raising a runtime error at the
+// name if it is undefined.
+func (i *Interpreter) getGlobal(name Token) interface{} {
+	value, ok := i.GlobalEnv.Lookup(name.Lexeme)
+	if !ok {
+		panic(runtimeErr(name, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme)))
+	}
+	return value
+}
+
+// assignGlobal is the counterpart of getGlobal that sets the variable.
+func (i *Interpreter) assignGlobal(name Token, value interface{}) {
+	if !i.GlobalEnv.Assign(name.Lexeme, value) {
+		panic(runtimeErr(name, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme)))
+	}
 }
 
 // VisitLogicalExpr ...
-func (i Interpreter) VisitLogicalExpr(expr *ExprLogical) interface{} {
+func (i *Interpreter) VisitLogicalExpr(expr *ExprLogical) interface{} {
 	left := i.evaluate(expr.Left)
 	if expr.Operator.Type == TokenTypeOr {
 		if i.isTruthy(left) {
@@ -50,48 +143,126 @@ func (i Interpreter) VisitLogicalExpr(expr *ExprLogical) interface{} {
 	return i.evaluate(expr.Right)
 }
 
-// Interpret ...
-func (i Interpreter) Interpret(statements []Stmt) {
+// Interpret runs statements, which Parse has resolved. It prints runtime
+// errors and returns them as diagnostics. The statements are only read, so
+// one parsed program can run in several interpreters at once.
+func (i *Interpreter) Interpret(statements []Stmt) Diagnostics {
+	i.reporter.start(false)
+	i.run(i.limits.ctx, statements)
+	return i.reporter.stop().Diagnostics()
+}
+
+// run executes statements, then the event loop, and returns the value of
+// the last statement if it is an expression. It stops early once ctx is
+// done.
+func (i *Interpreter) run(ctx context.Context, statements []Stmt) (value interface{}) {
+	i.begin(ctx)
+	i.tasks.enter(i.task)
+	defer i.tasks.leave(i.task)
+	defer func() {
+		if r := recover(); r != nil {
+			switch e := r.(type) {
+			case *RuntimeError:
+				i.reporter.report(e)
+			case *DeadlockError:
+				i.reporter.report(e)
+			case *LimitError:
+				i.reporter.report(e)
+			default:
+				panic(r)
+			}
+		}
+	}()
 	for _, statement := range statements {
-		i.execute(statement)
+		i.step(0)
+		value = i.execute(statement)
+		if _, ok := statement.(*ExpressionStmt); !ok {
+			value = nil
+		}
 	}
+	i.loop.Run()
+	return value
 }
 
-func (i Interpreter) execute(stmt Stmt) interface{} {
+func (i *Interpreter) execute(stmt Stmt) interface{} {
 	return stmt.Accept(i)
 }
 
-func (i Interpreter) evaluate(expr Expr) interface{} {
+func (i *Interpreter) evaluate(expr Expr) interface{} {
 	return expr.Accept(i)
 }
 
 // VisitAssignExpr ...
-func (i Interpreter) VisitAssignExpr(expr *ExprAssign) interface{} {
+func (i *Interpreter) VisitAssignExpr(expr *ExprAssign) interface{} {
 	value := i.evaluate(expr.Value)
-	i.Env.Assign(expr.Name.Lexeme, value)
+	if b := expr.Binding; b != nil {
+		i.Env.AssignAt(b.Depth, b.Slot, value)
+	} else {
+		i.assignGlobal(expr.Name, value)
+	}
 	return value
 }
 
 // VisitUnaryExpr ...
-func (i Interpreter) VisitUnaryExpr(expr *ExprUnary) interface{} {
-	right := i.evaluate(expr.Right)
-	switch expr.Operator.Type {
+func (i *Interpreter) VisitUnaryExpr(expr *ExprUnary) interface{} {
+	return i.unaryOp(expr.Operator, i.evaluate(expr.Right))
+}
+
+// unaryOp is the unary counterpart of binaryOp.
+func (i *Interpreter) unaryOp(operator Token, right interface{}) interface{} {
+	switch operator.Type {
 	case TokenTypeBang:
-		return !i.isTruthy(right.(float64))
+		return !i.isTruthy(right)
 	case TokenTypeMinus:
-		return -right.(float64)
+		return -checkNumberOperand(operator, right)
 	}
 	return nil
 }
 
-func (i Interpreter) isTruthy(obj interface{}) bool {
+func checkNumberOperand(operator Token, operand interface{}) float64 {
+	if n, ok := operand.(float64); ok {
+		return n
+	}
+	panic(runtimeErr(operator, "Operand must be a number."))
+}
+
+func checkNumberOperands(operator Token, left interface{}, right interface{}) (float64, float64) {
+	a, okLeft := left.(float64)
+	b, okRight := right.(float64)
+	if !okLeft || !okRight {
+		panic(runtimeErr(operator, "Operands must be numbers."))
+	}
+	return a, b
+}
+
+// isEqual implements '==': nil equals only nil, numbers, strings and
+// booleans compare by value, and everything else by identity. Values of
+// different types are never equal.
+func isEqual(left interface{}, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if reflect.TypeOf(left) != reflect.TypeOf(right) || !reflect.TypeOf(left).Comparable() {
+		return false
+	}
+	return left == right
+}
+
+// isTruthy reports whether obj counts as true in conditions. In the
+// extended dialect empty strings and zero are false as well as nil and
+// false.
+func (i *Interpreter) isTruthy(obj interface{}) bool {
 	if obj == nil {
 		return false
 	}
+	if i.dialect == DialectStrict {
+		b, ok := obj.(bool)
+		return !ok || b
+	}
 	switch obj.(type) {
 	case string:
 		return !(obj.(string) == "")
-	case int, float64:
+	case float64:
 		return !(obj.(float64) == float64(0))
 	case bool:
 		return bool(obj.(bool))
@@ -101,7 +272,7 @@ func (i Interpreter) isTruthy(obj interface{}) bool {
 }
 
 // VisitCallExpr ...
-func (i Interpreter) VisitCallExpr(expr *ExprCall) interface{} {
+func (i *Interpreter) VisitCallExpr(expr *ExprCall) interface{} {
 	callee := i.evaluate(expr.Callee)
 	arguments := make([]interface{}, 0)
 	for _, arg := range expr.Arguments {
@@ -109,123 +280,336 @@ func (i Interpreter) VisitCallExpr(expr *ExprCall) interface{} {
 	}
 	f, ok := callee.(Callable)
 	if ok {
-		if len(arguments) != f.Arity() {
-			fmt.Printf("Expected %d arguments but got %d\n", f.Arity(), len(arguments))
-			return nil
+		if !arityMatches(f, len(arguments)) {
+			panic(runtimeErr(expr.Paren, fmt.Sprintf("Expected %d arguments but got %d.", f.Arity(), len(arguments))))
 		}
-		return f.Call(&i, arguments)
+		return i.callAt(f, arguments, expr.Paren)
 	}
-	fmt.Printf("Can only call functions and classes, not %v.\n", reflect.TypeOf(callee))
-	return nil
+	panic(runtimeErr(expr.Paren, "Can only call functions and classes."))
+}
+
+// callAt calls f with the checked arguments one call deeper, with a frame
+// for the call made at paren. The depth and frames are restored when f
+// returns or panics.
+func (i *Interpreter) callAt(f Callable, arguments []interface{}, paren Token) interface{} {
+	i.step(paren.Line)
+	depth, frames := i.depth, i.frames
+	i.depth++
+	i.enterCall(i.depth, paren.Line)
+	name := ""
+	if fn, ok := f.(*Function); ok {
+		name = fn.Declaration.Name.Lexeme
+	}
+	i.frames = &frame{function: name, call: paren, caller: i.frames}
+	// Natives raise runtime errors without a line; attribute them to
+	// the call site.
+	defer func() {
+		if r := recover(); r != nil {
+			if re, ok := r.(*RuntimeError); ok && re.Line == 0 {
+				re.Line = paren.Line
+				re.Token = &paren
+			}
+			i.traced(r)
+			i.depth, i.frames = depth, frames
+			panic(r)
+		}
+		i.depth, i.frames = depth, frames
+	}()
+	return f.Call(i, arguments)
+}
+
+// VisitGetExpr ...
+func (i *Interpreter) VisitGetExpr(expr *ExprGet) interface{} {
+	return getProperty(i.evaluate(expr.Object), expr.Name)
+}
+
+// VisitSetExpr ...
+func (i *Interpreter) VisitSetExpr(expr *ExprSet) interface{} {
+	object := i.evaluate(expr.Object)
+	value := i.evaluate(expr.Value)
+	setProperty(object, expr.Name, value)
+	return value
 }
 
 // VisitBinaryExpr ...
-func (i Interpreter) VisitBinaryExpr(expr *ExprBinary) interface{} {
+func (i *Interpreter) VisitBinaryExpr(expr *ExprBinary) interface{} {
 	left := i.evaluate(expr.Left)
 	right := i.evaluate(expr.Right)
+	return i.binaryOp(expr.Operator, left, right)
+}
+
+// binaryOp applies a binary operator to evaluated operands. It is shared by
+// every execution engine so operators behave the same in all of them.
+func (i *Interpreter) binaryOp(operator Token, left interface{}, right interface{}) interface{} {
+	if result, ok := hostOperator(operator, left, right); ok {
+		return result
+	}
 
-	switch expr.Operator.Type {
+	switch operator.Type {
+	case TokenTypeBangEqual:
+		return !isEqual(left, right)
+	case TokenTypeEqualEqual:
+		return isEqual(left, right)
+	case TokenTypePlus:
+		switch a := left.(type) {
+		case float64:
+			if b, ok := right.(float64); ok {
+				return a + b
+			}
+		case string:
+			if b, ok := right.(string); ok {
+				i.allocate(len(a)+len(b), operator.Line)
+				return a + b
+			}
+		}
+		// In the extended dialect, a string concatenated with any other
+		// value converts it.
+		_, leftString := left.(string)
+		_, rightString := right.(string)
+		if (leftString || rightString) && i.dialect == DialectExtended {
+			s := i.stringify(left, operator.Span()) + i.stringify(right, operator.Span())
+			i.allocate(len(s), operator.Line)
+			return s
+		}
+		panic(runtimeErr(operator, "Operands must be two numbers or two strings."))
+	case TokenTypeDotDot, TokenTypeDotDotEqual:
+		start, okStart := left.(float64)
+		end, okEnd := right.(float64)
+		if !okStart || !okEnd {
+			panic(runtimeErr(operator, "Range bounds must be numbers."))
+		}
+		if msg := checkRange(start, end, 1); msg != "" {
+			panic(runtimeErr(operator, msg))
+		}
+		return NewRange(start, end, operator.Type == TokenTypeDotDotEqual)
+	}
+
+	a, b := checkNumberOperands(operator, left, right)
+	switch operator.Type {
 	case TokenTypeGreater:
-		return left.(float64) > right.(float64)
+		return a > b
 	case TokenTypeGreaterEqual:
-		return left.(float64) >= right.(float64)
+		return a >= b
 	case TokenTypeLess:
-		return left.(float64) < right.(float64)
+		return a < b
 	case TokenTypeLessEqual:
-		return left.(float64) <= right.(float64)
-	case TokenTypeBangEqual:
-		return !(left.(float64) == right.(float64))
-	case TokenTypeEqualEqual:
-		return left.(float64) == right.(float64)
+		return a <= b
 	case TokenTypeMinus:
-		return left.(float64) - right.(float64)
-	case TokenTypePlus:
-		typeLeft := reflect.TypeOf(left).String()
-		typeRight := reflect.TypeOf(right).String()
-		if (typeLeft == "float64" || typeLeft == "float32" || typeLeft == "int") && (typeRight == "float64" || typeRight == "float32" || typeRight == "int") {
-			return left.(float64) + right.(float64)
-		} else if typeLeft == "string" && typeRight == "string" {
-			return left.(string) + right.(string)
-		}
+		return a - b
 	case TokenTypeSlash:
-		return left.(float64) / right.(float64)
+		return a / b
 	case TokenTypeStar:
-		return left.(float64) * right.(float64)
+		return a * b
 	}
 	return nil
 }
 
 // VisitExpressionStmt ...
-func (i Interpreter) VisitExpressionStmt(stmt *ExpressionStmt) interface{} {
+func (i *Interpreter) VisitExpressionStmt(stmt *ExpressionStmt) interface{} {
 	return i.evaluate(stmt.Expression)
 }
 
 // VisitPrintStmt ...
-func (i Interpreter) VisitPrintStmt(stmt *PrintStmt) interface{} {
+func (i *Interpreter) VisitPrintStmt(stmt *PrintStmt) interface{} {
 	value := i.evaluate(stmt.Expression)
-	fmt.Printf("%v\n", value)
+	fmt.Fprintln(i.stdout, i.stringify(value, stmt.Span()))
 	return nil
 }
 
 // VisitVarStmt ...
-func (i Interpreter) VisitVarStmt(stmt *VarStmt) interface{} {
+func (i *Interpreter) VisitVarStmt(stmt *VarStmt) interface{} {
 	var value interface{}
 	if stmt.Initializer != nil {
 		value = i.evaluate(stmt.Initializer)
 	}
-	i.Env.Define(stmt.Name.Lexeme, value)
+	i.define(stmt.Name.Lexeme, stmt.Slot, value)
 	return nil
 }
 
+// define binds a declared variable: by name when it is a global, and in
+// its slot of the current environment otherwise.
+func (i *Interpreter) define(name string, slot int, value interface{}) {
+	if slot < 0 {
+		i.GlobalEnv.Define(name, value)
+		return
+	}
+	i.Env.AssignAt(0, slot, value)
+}
+
 // VisitBlockStmt ...
-func (i Interpreter) VisitBlockStmt(stmt *BlockStmt) interface{} {
-	i.ExecuteBlock(stmt.Statements, NewEnvironment(i.Env))
-	return nil
+func (i *Interpreter) VisitBlockStmt(stmt *BlockStmt) interface{} {
+	return i.ExecuteBlock(stmt.Statements, NewScope(i.Env, stmt.Slots))
 }
 
-// ExecuteBlock ...
-func (i Interpreter) ExecuteBlock(statements []Stmt, env *Environment) {
+// ExecuteBlock runs statements in env. It stops at the first statement that
+// returns a *ReturnValue and hands it back so callers can unwind to the
+// enclosing function. The previous environment is restored even when a
+// statement panics, so the interpreter can run again after an error.
+func (i *Interpreter) ExecuteBlock(statements []Stmt, env *Environment) interface{} {
 	previous := i.Env
 	i.Env = env
+	defer func() { i.Env = previous }()
 	for _, statement := range statements {
-		i.execute(statement)
+		i.step(0)
+		if rv, ok := i.execute(statement).(*ReturnValue); ok {
+			return rv
+		}
 	}
-	i.Env = previous
+	return nil
 }
 
 // VisitIfStmt ...
-func (i Interpreter) VisitIfStmt(stmt *IfStmt) interface{} {
+func (i *Interpreter) VisitIfStmt(stmt *IfStmt) interface{} {
 	if i.isTruthy(i.evaluate(stmt.Condition)) {
-		i.execute(stmt.ThenBranch)
+		return i.execute(stmt.ThenBranch)
 	} else if stmt.ElseBranch != nil {
-		i.execute(stmt.ElseBranch)
+		return i.execute(stmt.ElseBranch)
 	}
 	return nil
 }
 
 // VisitWhileStmt ...
-func (i Interpreter) VisitWhileStmt(stmt *WhileStmt) interface{} {
+func (i *Interpreter) VisitWhileStmt(stmt *WhileStmt) interface{} {
 	for {
+		i.step(0)
 		if !i.isTruthy(i.evaluate(stmt.Condition)) {
 			break
 		}
-		i.execute(stmt.Body)
+		if rv, ok := i.execute(stmt.Body).(*ReturnValue); ok {
+			return rv
+		}
 	}
 	return nil
 }
 
 // VisitFunctionStmt ...
-func (i Interpreter) VisitFunctionStmt(stmt *FunctionStmt) interface{} {
-	f := NewFunction(*stmt)
-	i.Env.Define(stmt.Name.Lexeme, f)
+func (i *Interpreter) VisitFunctionStmt(stmt *FunctionStmt) interface{} {
+	f := NewFunction(*stmt, i.Env)
+	i.define(stmt.Name.Lexeme, stmt.Slot, f)
 	return nil
 }
 
 // VisitReturnStmt ...
-func (i Interpreter) VisitReturnStmt(stmt *ReturnStmt) interface{} {
+func (i *Interpreter) VisitReturnStmt(stmt *ReturnStmt) interface{} {
 	var value interface{}
 	if stmt.Value != nil {
 		value = i.evaluate(stmt.Value)
 	}
 	return &ReturnValue{ExprLiteral{Value: value}}
 }
+
+// VisitYieldStmt ...
+func (i *Interpreter) VisitYieldStmt(stmt *YieldStmt) interface{} {
+	var value interface{}
+	if stmt.Value != nil {
+		value = i.evaluate(stmt.Value)
+	}
+	if i.co == nil {
+		panic(&RuntimeError{Line: stmt.Keyword.Line, Msg: "Can't yield outside of a generator."})
+	}
+	i.co.yield(value)
+	return nil
+}
+
+// VisitSpawnStmt ...
+func (i *Interpreter) VisitSpawnStmt(stmt *SpawnStmt) interface{} {
+	callee := i.evaluate(stmt.Call.Callee)
+	arguments := make([]interface{}, 0)
+	for _, arg := range stmt.Call.Arguments {
+		arguments = append(arguments, i.evaluate(*arg))
+	}
+	f, ok := callee.(Callable)
+	if !ok {
+		panic(&RuntimeError{Line: stmt.Keyword.Line, Msg: "Can only spawn functions."})
+	}
+	if !arityMatches(f, len(arguments)) {
+		panic(&RuntimeError{Line: stmt.Keyword.Line, Msg: fmt.Sprintf("Expected %d arguments but got %d.", f.Arity(), len(arguments))})
+	}
+	name := "native fn"
+	if fn, ok := f.(*Function); ok {
+		name = fn.Declaration.Name.Lexeme
+	}
+
+	ti := *i
+	ti.co = nil
+	ti.depth = 0
+	// The traceback of a task goes back to where it was spawned.
+	ti.frames = &frame{function: name, call: stmt.Keyword, caller: i.frames}
+	ti.task = i.tasks.spawn(name, stmt.Keyword.Line)
+	go func() {
+		defer ti.tasks.exit(ti.task)
+		defer func() {
+			if r := recover(); r != nil {
+				switch e := r.(type) {
+				case taskAborted:
+				case *RuntimeError:
+					ti.traced(e)
+					ti.reporter.report(e)
+				case *LimitError:
+					ti.reporter.report(e)
+				default:
+					panic(r)
+				}
+			}
+		}()
+		f.Call(&ti, arguments)
+	}()
+	return nil
+}
+
+// VisitSelectStmt ...
+func (i *Interpreter) VisitSelectStmt(stmt *SelectStmt) interface{} {
+	ops := make([]chanOp, len(stmt.Cases))
+	for idx, c := range stmt.Cases {
+		ch, ok := i.evaluate(c.Channel).(*Channel)
+		if !ok {
+			panic(&RuntimeError{Line: c.Operation.Line, Msg: fmt.Sprintf("%s() in select expects a channel.", c.Operation.Lexeme)})
+		}
+		ops[idx] = chanOp{ch: ch}
+		if c.Value != nil {
+			ops[idx].send = true
+			ops[idx].value = i.evaluate(c.Value)
+		}
+	}
+
+	blockedOn := fmt.Sprintf("select at line %d", stmt.Keyword.Line)
+	fired, value, _ := i.tasks.choose(i.task, ops, stmt.Default == nil, blockedOn)
+	if fired < 0 {
+		return i.ExecuteBlock(stmt.Default, NewScope(i.Env, stmt.DefaultSlots))
+	}
+	c := stmt.Cases[fired]
+	env := NewScope(i.Env, c.Slots)
+	if c.Name != nil {
+		env.Slots[0] = value
+	}
+	return i.ExecuteBlock(c.Body, env)
+}
+
+// VisitAwaitExpr suspends the enclosing async function until the promise
+// settles. Outside async functions it runs the event loop until then.
+func (i *Interpreter) VisitAwaitExpr(expr *ExprAwait) interface{} {
+	value := i.evaluate(expr.Value)
+	p, ok := value.(*Promise)
+	if !ok {
+		return value
+	}
+
+	var result awaitResult
+	if i.async != nil {
+		result = i.async.yield(p).(awaitResult)
+	} else {
+		loop := i.mainLoop()
+		p.handled = true
+		loop.runUntil(func() bool {
+			return p.state != promisePending
+		})
+		if p.state == promisePending {
+			panic(&RuntimeError{Line: expr.Keyword.Line, Msg: "Awaited promise can never settle."})
+		}
+		result = awaitResult{value: p.value, err: p.err}
+	}
+	if result.err != nil {
+		panic(result.err)
+	}
+	return result.value
+}
@@ -0,0 +1,134 @@
+package lox
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Iterator is a stateful cursor over a sequence of values.
+type Iterator interface {
+	HasNext() bool
+	Next() interface{}
+}
+
+// Iterable is implemented by values that can produce a fresh Iterator.
+type Iterable interface {
+	Iterator() Iterator
+}
+
+// Container is implemented by values supporting membership tests.
+type Container interface {
+	Contains(value interface{}) bool
+}
+
+func toIterator(value interface{}) (Iterator, bool) {
+	switch v := value.(type) {
+	case Iterator:
+		return v, true
+	case Iterable:
+		return v.Iterator(), true
+	}
+	return nil, false
+}
+
+// Iter ...
+type Iter struct{}
+
+// Arity ...
+func (it Iter) Arity() int {
+	return 1
+}
+
+// Call ...
+func (it Iter) Call(i *Interpreter, args []interface{}) interface{} {
+	iterator, ok := toIterator(args[0])
+	if !ok {
+		panic(&RuntimeError{Msg: fmt.Sprintf("Can only iterate over ranges, lists and iterators, not %v.", reflect.TypeOf(args[0]))})
+	}
+	return iterator
+}
+
+// HasNext ...
+type HasNext struct{}
+
+// Arity ...
+func (hn HasNext) Arity() int {
+	return 1
+}
+
+// Call ...
+func (hn HasNext) Call(i *Interpreter, args []interface{}) interface{} {
+	iterator, ok := args[0].(Iterator)
+	if !ok {
+		panic(&RuntimeError{Msg: "hasNext() expects an iterator."})
+	}
+	return iterator.HasNext()
+}
+
+// Next ...
+type Next struct{}
+
+// Arity ...
+func (n Next) Arity() int {
+	return 1
+}
+
+// Call ...
+func (n Next) Call(i *Interpreter, args []interface{}) interface{} {
+	iterator, ok := args[0].(Iterator)
+	if !ok {
+		panic(&RuntimeError{Msg: "next() expects an iterator."})
+	}
+	return iterator.Next()
+}
+
+// maxRangeList is the length of the longest list list() makes from a
+// range, which is far beyond what fits in memory for most hosts. Longer
+// ranges raise a runtime error rather than exhausting memory.
+const maxRangeList = 1 << 27
+
+// ToList ...
+type ToList struct{}
+
+// Arity ...
+func (tl ToList) Arity() int {
+	return 1
+}
+
+// Call ...
+func (tl ToList) Call(i *Interpreter, args []interface{}) interface{} {
+	iterator, ok := toIterator(args[0])
+	if !ok {
+		panic(&RuntimeError{Msg: fmt.Sprintf("Can only convert ranges, lists and iterators to a list, not %v.", reflect.TypeOf(args[0]))})
+	}
+	elements := make([]interface{}, 0)
+	if r, ok := args[0].(*Range); ok {
+		if r.Len() > maxRangeList {
+			panic(&RuntimeError{Msg: "Range is too large to convert to a list."})
+		}
+		i.allocate(r.Len(), 0)
+		elements = make([]interface{}, 0, r.Len())
+	}
+	for iterator.HasNext() {
+		i.allocate(len(elements)+1, 0)
+		elements = append(elements, iterator.Next())
+	}
+	return NewList(elements)
+}
+
+// Contains ...
+type Contains struct{}
+
+// Arity ...
+func (c Contains) Arity() int {
+	return 2
+}
+
+// Call ...
+func (c Contains) Call(i *Interpreter, args []interface{}) interface{} {
+	container, ok := args[0].(Container)
+	if !ok {
+		panic(&RuntimeError{Msg: fmt.Sprintf("Can only test membership in ranges and lists, not %v.", reflect.TypeOf(args[0]))})
+	}
+	return container.Contains(args[1])
+}
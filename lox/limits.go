@@ -0,0 +1,157 @@
+package lox
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// The errors wrapped by the LimitError that stops a script exceeding one
+// of the limits set with the interpreter options.
+var (
+	ErrStepLimit       = errors.New("Step limit exceeded.")
+	ErrCallDepthLimit  = errors.New("Call depth limit exceeded.")
+	ErrAllocationLimit = errors.New("Allocation limit exceeded.")
+)
+
+// LimitError stops a script that exceeds a limit or whose context is done.
+// Err is ErrStepLimit, ErrCallDepthLimit, ErrAllocationLimit or the error
+// of the context, and can be tested for with errors.Is. Unlike a runtime
+// error, it stops the whole program: it does not reject promises and ends
+// the event loop.
+type LimitError struct {
+	Line int
+	Err  error
+}
+
+// Error ...
+func (le *LimitError) Error() string {
+	if le.Line == 0 {
+		return le.Err.Error()
+	}
+	return report(le.Line, "", le.Err.Error())
+}
+
+// Unwrap ...
+func (le *LimitError) Unwrap() error {
+	return le.Err
+}
+
+// DefaultMaxRecursion is how deeply calls may nest before a script fails
+// with a stack overflow, unless set with WithMaxRecursion.
+const DefaultMaxRecursion = 1024
+
+// limits are shared by the copies of an interpreter. A limit of zero means
+// no limit.
+type limits struct {
+	ctx           context.Context
+	maxSteps      int64
+	maxCallDepth  int
+	maxAllocation int
+	maxRecursion  int
+
+	// run is the context of the current run and done its channel. steps
+	// counts the steps of the run across tasks; counting reports whether
+	// there is anything to check a step against.
+	run      context.Context
+	done     <-chan struct{}
+	steps    int64
+	counting bool
+}
+
+// WithContext makes Interpret, Eval, Call and the other engines stop the
+// script once ctx is done. Run uses its own context instead.
+func WithContext(ctx context.Context) Option {
+	return func(i *Interpreter) {
+		i.limits.ctx = ctx
+	}
+}
+
+// WithMaxSteps limits how many steps a run may take. The tree-walker and
+// the closure compiler count statements in blocks, loop iterations and
+// calls; the VM counts instructions.
+func WithMaxSteps(n int64) Option {
+	return func(i *Interpreter) {
+		i.limits.maxSteps = n
+	}
+}
+
+// WithMaxCallDepth limits how deeply calls may nest.
+func WithMaxCallDepth(n int) Option {
+	return func(i *Interpreter) {
+		i.limits.maxCallDepth = n
+	}
+}
+
+// WithMaxRecursion sets how deeply calls may nest before the script fails
+// with a "Stack overflow." runtime error. Unlike the limit set with
+// WithMaxCallDepth, it guards against runaway recursion in trusted scripts
+// and is on by default; zero removes it, leaving only the Go stack.
+func WithMaxRecursion(n int) Option {
+	return func(i *Interpreter) {
+		i.limits.maxRecursion = n
+	}
+}
+
+// WithMaxAllocation limits the length of the strings and lists a script
+// may create.
+func WithMaxAllocation(n int) Option {
+	return func(i *Interpreter) {
+		i.limits.maxAllocation = n
+	}
+}
+
+// begin starts a run that stops once ctx is done, counting steps from
+// zero. The event loop stops with it.
+func (i *Interpreter) begin(ctx context.Context) {
+	l := i.limits
+	l.run = ctx
+	l.done = ctx.Done()
+	l.counting = l.done != nil || l.maxSteps > 0
+	atomic.StoreInt64(&l.steps, 0)
+	i.loop.ctx = ctx
+}
+
+// step counts a step of the script. It is cheap enough to call on every
+// statement when there are no limits.
+func (i *Interpreter) step(line int) {
+	if i.limits.counting {
+		i.limits.step(line)
+	}
+}
+
+// step stops the script once its context is done or it has used up its
+// steps.
+func (l *limits) step(line int) {
+	if l.done != nil {
+		select {
+		case <-l.done:
+			panic(&LimitError{line, l.run.Err()})
+		default:
+		}
+	}
+	if l.maxSteps > 0 && atomic.AddInt64(&l.steps, 1) > l.maxSteps {
+		panic(&LimitError{line, ErrStepLimit})
+	}
+}
+
+// enterCall checks the depth of a call about to be made.
+func (i *Interpreter) enterCall(depth int, line int) {
+	if max := i.limits.maxCallDepth; max > 0 && depth > max {
+		panic(&LimitError{line, ErrCallDepthLimit})
+	}
+	if max := i.limits.maxRecursion; max > 0 && depth > max {
+		panic(&RuntimeError{Line: line, Msg: "Stack overflow."})
+	}
+}
+
+// allocate checks the length of a string or list about to be created.
+// Natives may be called without an interpreter, and then have no limit.
+func (i *Interpreter) allocate(size int, line int) {
+	if i == nil {
+		return
+	}
+	if max := i.limits.maxAllocation; max > 0 && size > max {
+		panic(&LimitError{line, ErrAllocationLimit})
+	}
+}
@@ -0,0 +1,116 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLimits ...
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		source string
+		opt    Option
+		err    error
+	}{
+		{"while (true) {}", WithMaxSteps(1000), ErrStepLimit},
+		{"fun f(n) { return f(n + 1); } f(0);", WithMaxCallDepth(50), ErrCallDepthLimit},
+		{`var s = "ab"; while (true) s = s + s;`, WithMaxAllocation(1 << 10), ErrAllocationLimit},
+		{"list(0..1000000);", WithMaxAllocation(1000), ErrAllocationLimit},
+	}
+	for _, test := range tests {
+		_, err := NewInterpreter(test.opt).Eval(test.source)
+		var le *LimitError
+		if !errors.Is(err, test.err) || !errors.As(err, &le) {
+			t.Errorf("%s: got %v, expected %v", test.source, err, test.err)
+		}
+	}
+
+	// Each run gets its own steps.
+	i := NewInterpreter(WithMaxSteps(100))
+	for n := 0; n < 3; n++ {
+		if _, err := i.Eval("for (var k = 0; k < 20; k = k + 1) {}"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestLimitContext ...
+func TestLimitContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := NewInterpreter().Run(ctx, "while (true) {}")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, expected the loop to stop at the deadline", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = NewInterpreter(WithContext(ctx)).Eval("fun tick() {} setTimeout(tick, 60000);")
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Errorf("got %v, expected the event loop to stop at the deadline", err)
+	}
+}
+
+// TestLimitEngines ...
+func TestLimitEngines(t *testing.T) {
+	sources := map[string]string{
+		"Step limit exceeded.":       "while (true) {}",
+		"Call depth limit exceeded.": "fun f(n) { return f(n + 1); } f(0);",
+		"Allocation limit exceeded.": `var s = "ab"; while (true) s = s + s;`,
+	}
+	for want, source := range sources {
+		for name, run := range map[string]func(*Interpreter, []Stmt){
+			"vm": func(i *Interpreter, stmts []Stmt) { NewVM(i).Interpret(stmts) },
+			"closure": func(i *Interpreter, stmts []Stmt) {
+				program, _ := CompileClosures(stmts)
+				program.Run(i)
+			},
+		} {
+			stderr := bytes.Buffer{}
+			i := NewInterpreter(WithStderr(&stderr), WithMaxSteps(100000), WithMaxCallDepth(50), WithMaxAllocation(1<<10))
+			stmts, err := Parse(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			run(i, stmts)
+			if !strings.Contains(stderr.String(), want) {
+				t.Errorf("%s: %s: got %q, expected %q", name, source, stderr.String(), want)
+			}
+		}
+	}
+}
+
+// TestStackOverflow checks that unbounded recursion fails with a runtime
+// error in every engine instead of exhausting the Go stack, and that the
+// interpreter keeps working afterwards.
+func TestStackOverflow(t *testing.T) {
+	stmts, err := Parse("fun f(n) { return f(n + 1); }\nf(0);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, run := range engines(stmts) {
+		i := NewInterpreter(WithStderr(&bytes.Buffer{}))
+		ds := run(i)
+		if len(ds) != 1 || ds[0].Message != "Stack overflow." || ds[0].Phase != PhaseRuntime {
+			t.Errorf("%s: got %v, expected a stack overflow", name, ds)
+		}
+		if value, err := i.Eval("1 + 1;"); err != nil || value != 2.0 {
+			t.Errorf("%s: got %v, %v after the stack overflow", name, value, err)
+		}
+	}
+
+	source := "fun down(n) { if (n > 0) return down(n - 1); return n; } down(%d);"
+	i := NewInterpreter(WithStderr(&bytes.Buffer{}), WithMaxRecursion(100))
+	if _, err := i.Eval(fmt.Sprintf(source, 90)); err != nil {
+		t.Errorf("got %v within the recursion limit", err)
+	}
+	if _, err := i.Eval(fmt.Sprintf(source, 200)); err == nil || !strings.Contains(err.Error(), "Stack overflow.") {
+		t.Errorf("got %v, expected a stack overflow beyond the limit", err)
+	}
+}
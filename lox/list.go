@@ -0,0 +1,63 @@
+package lox
+
+import (
+	"bytes"
+)
+
+// List ...
+type List struct {
+	Elements []interface{}
+}
+
+// NewList ...
+func NewList(elements []interface{}) *List {
+	return &List{Elements: elements}
+}
+
+// Contains reports whether an element of the list is equal to value, as
+// '==' compares them without host operators.
+func (l *List) Contains(value interface{}) bool {
+	for _, element := range l.Elements {
+		if isEqual(element, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Iterator ...
+func (l *List) Iterator() Iterator {
+	return &listIterator{l: l}
+}
+
+// String ...
+func (l *List) String() string {
+	buf := bytes.Buffer{}
+	buf.WriteRune('[')
+	for idx, element := range l.Elements {
+		if idx > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(Stringify(element))
+	}
+	buf.WriteRune(']')
+	return buf.String()
+}
+
+type listIterator struct {
+	l   *List
+	idx int
+}
+
+func (it *listIterator) HasNext() bool {
+	return it.idx < len(it.l.Elements)
+}
+
+func (it *listIterator) Next() interface{} {
+	if !it.HasNext() {
+		return nil
+	}
+	value := it.l.Elements[it.idx]
+	it.idx++
+	return value
+}
@@ -0,0 +1,156 @@
+package lox
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Object is implemented by values whose properties scripts read and write
+// with obj.name and obj.name = value.
+type Object interface {
+	GetProperty(name string) (interface{}, error)
+	SetProperty(name string, value interface{}) error
+}
+
+// HostObject is the proxy through which scripts use Go structs, pointers
+// to structs and maps with string keys. Properties are exported fields,
+// methods and map entries; values are converted as for RegisterFunc.
+//
+// Host values need not be wrapped: property access on them creates a
+// proxy, so they keep working with the host operator interfaces.
+type HostObject struct {
+	v reflect.Value
+}
+
+// NewHostObject ...
+func NewHostObject(value interface{}) *HostObject {
+	return &HostObject{v: reflect.ValueOf(value)}
+}
+
+// GetProperty ...
+func (h *HostObject) GetProperty(name string) (interface{}, error) {
+	if method := h.v.MethodByName(name); method.IsValid() {
+		return &HostFunc{name: name, fn: method}, nil
+	}
+	v := reflect.Indirect(h.v)
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := v.Type().FieldByName(name)
+		if !ok || field.PkgPath != "" {
+			break
+		}
+		value := v.FieldByIndex(field.Index)
+		// Nested structs are returned by reference so that scripts can
+		// assign their fields.
+		if value.Kind() == reflect.Struct && value.CanAddr() {
+			return value.Addr().Interface(), nil
+		}
+		return fromGo(value), nil
+	case reflect.Map:
+		key, err := h.mapKey(v, name)
+		if err != nil {
+			return nil, err
+		}
+		if value := v.MapIndex(key); value.IsValid() {
+			return fromGo(value), nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("Undefined property '%s'.", name)
+}
+
+// SetProperty ...
+func (h *HostObject) SetProperty(name string, value interface{}) error {
+	v := reflect.Indirect(h.v)
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := v.Type().FieldByName(name)
+		if !ok || field.PkgPath != "" {
+			return fmt.Errorf("Undefined property '%s'.", name)
+		}
+		if !v.CanSet() {
+			return fmt.Errorf("Can't set '%s' on a copy of %v; pass a pointer.", name, v.Type())
+		}
+		converted, err := toGo(value, field.Type)
+		if err != nil {
+			return fmt.Errorf("Can't set '%s': %v.", name, err)
+		}
+		v.FieldByIndex(field.Index).Set(converted)
+		return nil
+	case reflect.Map:
+		key, err := h.mapKey(v, name)
+		if err != nil {
+			return err
+		}
+		if v.IsNil() {
+			return fmt.Errorf("Can't set '%s' on a nil map.", name)
+		}
+		converted, err := toGo(value, v.Type().Elem())
+		if err != nil {
+			return fmt.Errorf("Can't set '%s': %v.", name, err)
+		}
+		v.SetMapIndex(key, converted)
+		return nil
+	}
+	return fmt.Errorf("Can't set properties on %v.", h.v.Type())
+}
+
+func (h *HostObject) mapKey(m reflect.Value, name string) (reflect.Value, error) {
+	if m.Type().Key().Kind() != reflect.String {
+		return reflect.Value{}, fmt.Errorf("Properties need a map with string keys, not %v.", m.Type())
+	}
+	return reflect.ValueOf(name).Convert(m.Type().Key()), nil
+}
+
+// String ...
+func (h *HostObject) String() string {
+	return fmt.Sprintf("%v", h.v.Interface())
+}
+
+// asObject returns the Object through which properties of value are used.
+// Host structs and maps get a proxy; the runtime values of the language do
+// not have properties.
+func asObject(value interface{}) (Object, bool) {
+	switch value.(type) {
+	case Object:
+		return value.(Object), true
+	case nil, Callable, Iterator, Iterable, *Channel, *Promise:
+		return nil, false
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct {
+		return &HostObject{v: v}, true
+	}
+	if v.Kind() == reflect.Struct || v.Kind() == reflect.Map {
+		return &HostObject{v: v}, true
+	}
+	return nil, false
+}
+
+func getProperty(object interface{}, name Token) interface{} {
+	o, ok := asObject(object)
+	if !ok {
+		panic(runtimeErr(name, "Only objects have properties."))
+	}
+	value, err := o.GetProperty(name.Lexeme)
+	if err != nil {
+		panic(runtimeErr(name, err.Error()))
+	}
+	return value
+}
+
+func setProperty(object interface{}, name Token, value interface{}) {
+	o, ok := asObject(object)
+	if !ok {
+		panic(runtimeErr(name, "Only objects have fields."))
+	}
+	if err := o.SetProperty(name.Lexeme, value); err != nil {
+		panic(runtimeErr(name, err.Error()))
+	}
+}
+
+// Define makes a Go value available to scripts as the global name,
+// converted as the results of functions registered with RegisterFunc are.
+func (i *Interpreter) Define(name string, value interface{}) {
+	i.GlobalEnv.Define(name, fromGo(reflect.ValueOf(value)))
+}
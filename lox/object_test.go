@@ -0,0 +1,75 @@
+package lox
+
+import (
+	"fmt"
+	"testing"
+)
+
+type address struct {
+	City string
+}
+
+type account struct {
+	Owner   string
+	Balance float64
+	Tags    []string
+	Home    address
+	Limits  map[string]int
+	secret  string
+}
+
+func (a *account) Deposit(amount float64) float64 {
+	a.Balance += amount
+	return a.Balance
+}
+
+func (a account) Describe(prefix string) string {
+	return fmt.Sprintf("%s %s", prefix, a.Owner)
+}
+
+// TestHostObject ...
+func TestHostObject(t *testing.T) {
+	acct := &account{Owner: "ada", Balance: 10, Tags: []string{"a", "b"}, Limits: map[string]int{"daily": 5}, secret: "x"}
+	source := `
+acct.Deposit(5);
+acct.Balance = acct.Balance * 2;
+acct.Home.City = "london";
+acct.Limits.daily = acct.Limits.daily + 1;
+var owner = acct.Owner;
+var description = acct.Describe("owned by");
+var tags = acct.Tags;
+`
+	for engine, run := range engines(mustParse(source)) {
+		acct.Balance = 10
+		i := NewInterpreter()
+		i.Define("acct", acct)
+		run(i)
+		if acct.Balance != 30 || acct.Home.City != "london" {
+			t.Errorf("%s: got balance %v in %q", engine, acct.Balance, acct.Home.City)
+		}
+		if got := i.GlobalEnv.Get("description"); got != "owned by ada" {
+			t.Errorf("%s: got %v, expected owned by ada", engine, got)
+		}
+		if got := i.GlobalEnv.Get("owner"); got != "ada" {
+			t.Errorf("%s: got %v, expected ada", engine, got)
+		}
+		if got := Stringify(i.GlobalEnv.Get("tags")); got != "[a, b]" {
+			t.Errorf("%s: got %v, expected [a, b]", engine, got)
+		}
+	}
+	if acct.Limits["daily"] != 8 {
+		t.Errorf("got daily limit %d, expected 8", acct.Limits["daily"])
+	}
+
+	_, err := NewInterpreter().Eval("1.x;")
+	if err == nil {
+		t.Error("expected property access on a number to fail")
+	}
+	i := NewInterpreter()
+	i.Define("acct", acct)
+	for _, source := range []string{"acct.secret;", "acct.Missing = 1;", `acct.Balance = "x";`} {
+		if _, err := i.Eval(source); err == nil {
+			t.Errorf("%s: expected an error", source)
+		}
+	}
+}
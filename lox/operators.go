@@ -0,0 +1,147 @@
+package lox
+
+// The interfaces below let values injected by a Go host take part in Lox
+// operators. The interpreter consults them before its number and string
+// fast paths; a returned error becomes a runtime error at the operator.
+
+// Adder is implemented by host values supporting '+'.
+type Adder interface {
+	Add(right interface{}) (interface{}, error)
+}
+
+// Subtracter is implemented by host values supporting '-'.
+type Subtracter interface {
+	Sub(right interface{}) (interface{}, error)
+}
+
+// Multiplier is implemented by host values supporting '*'.
+type Multiplier interface {
+	Mul(right interface{}) (interface{}, error)
+}
+
+// Divider is implemented by host values supporting '/'.
+type Divider interface {
+	Div(right interface{}) (interface{}, error)
+}
+
+// RightAdder is implemented by host values supporting '+' with a left
+// operand that is not an Adder.
+type RightAdder interface {
+	RightAdd(left interface{}) (interface{}, error)
+}
+
+// RightSubtracter is implemented by host values supporting '-' with a left
+// operand that is not a Subtracter.
+type RightSubtracter interface {
+	RightSub(left interface{}) (interface{}, error)
+}
+
+// RightMultiplier is implemented by host values supporting '*' with a left
+// operand that is not a Multiplier.
+type RightMultiplier interface {
+	RightMul(left interface{}) (interface{}, error)
+}
+
+// RightDivider is implemented by host values supporting '/' with a left
+// operand that is not a Divider.
+type RightDivider interface {
+	RightDiv(left interface{}) (interface{}, error)
+}
+
+// Comparer is implemented by host values supporting '<', '<=', '>' and
+// '>='. Compare returns a negative number, zero or a positive number when
+// the receiver is less than, equal to or greater than other.
+type Comparer interface {
+	Compare(other interface{}) (int, error)
+}
+
+// Equaler is implemented by host values with their own notion of '=='.
+type Equaler interface {
+	Equal(other interface{}) bool
+}
+
+// Stringer is implemented by host values with a readable form for print.
+type Stringer interface {
+	String() string
+}
+
+// hostOperator applies operator through the interfaces above.
+// The left operand is asked first; arithmetic falls back to the Right
+// interfaces of the right operand, and comparisons and equality to the
+// right operand with the result mirrored. ok is false if neither operand
+// implements the operator.
+func hostOperator(operator Token, left interface{}, right interface{}) (result interface{}, ok bool) {
+	var e error
+	switch operator.Type {
+	case TokenTypePlus:
+		if adder, isLeft := left.(Adder); isLeft {
+			result, e = adder.Add(right)
+			ok = true
+		} else if adder, isRight := right.(RightAdder); isRight {
+			result, e = adder.RightAdd(left)
+			ok = true
+		}
+	case TokenTypeMinus:
+		if subtracter, isLeft := left.(Subtracter); isLeft {
+			result, e = subtracter.Sub(right)
+			ok = true
+		} else if subtracter, isRight := right.(RightSubtracter); isRight {
+			result, e = subtracter.RightSub(left)
+			ok = true
+		}
+	case TokenTypeStar:
+		if multiplier, isLeft := left.(Multiplier); isLeft {
+			result, e = multiplier.Mul(right)
+			ok = true
+		} else if multiplier, isRight := right.(RightMultiplier); isRight {
+			result, e = multiplier.RightMul(left)
+			ok = true
+		}
+	case TokenTypeSlash:
+		if divider, isLeft := left.(Divider); isLeft {
+			result, e = divider.Div(right)
+			ok = true
+		} else if divider, isRight := right.(RightDivider); isRight {
+			result, e = divider.RightDiv(left)
+			ok = true
+		}
+	case TokenTypeGreater, TokenTypeGreaterEqual, TokenTypeLess, TokenTypeLessEqual:
+		var order int
+		if comparer, isComparer := left.(Comparer); isComparer {
+			order, e = comparer.Compare(right)
+			ok = true
+		} else if comparer, isComparer := right.(Comparer); isComparer {
+			order, e = comparer.Compare(left)
+			order = -order
+			ok = true
+		}
+		if ok && e == nil {
+			result = compareResult(operator.Type, order)
+		}
+	case TokenTypeEqualEqual, TokenTypeBangEqual:
+		var equal bool
+		if equaler, isEqualer := left.(Equaler); isEqualer {
+			equal, ok = equaler.Equal(right), true
+		} else if equaler, isEqualer := right.(Equaler); isEqualer {
+			equal, ok = equaler.Equal(left), true
+		}
+		result = equal == (operator.Type == TokenTypeEqualEqual)
+	}
+	if e != nil {
+		panic(runtimeErr(operator, e.Error()))
+	}
+	return result, ok
+}
+
+func compareResult(operator TokenType, order int) bool {
+	switch operator {
+	case TokenTypeGreater:
+		return order > 0
+	case TokenTypeGreaterEqual:
+		return order >= 0
+	case TokenTypeLess:
+		return order < 0
+	default:
+		return order <= 0
+	}
+}
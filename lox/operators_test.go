@@ -0,0 +1,156 @@
+package lox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// money is a host value counting cents.
+type money struct {
+	cents int64
+}
+
+func (m money) Add(right interface{}) (interface{}, error) {
+	other, ok := right.(money)
+	if !ok {
+		return nil, errors.New("Can only add money to money.")
+	}
+	return money{m.cents + other.cents}, nil
+}
+
+func (m money) Mul(right interface{}) (interface{}, error) {
+	n, ok := right.(float64)
+	if !ok {
+		return nil, errors.New("Can only multiply money by a number.")
+	}
+	return money{int64(float64(m.cents) * n)}, nil
+}
+
+func (m money) RightMul(left interface{}) (interface{}, error) {
+	return m.Mul(left)
+}
+
+func (m money) Compare(other interface{}) (int, error) {
+	o, ok := other.(money)
+	if !ok {
+		return 0, errors.New("Can only compare money with money.")
+	}
+	return int(m.cents - o.cents), nil
+}
+
+func (m money) Equal(other interface{}) bool {
+	o, ok := other.(money)
+	return ok && o.cents == m.cents
+}
+
+func (m money) String() string {
+	return fmt.Sprintf("$%d.%02d", m.cents/100, m.cents%100)
+}
+
+// TestHostOperators ...
+func TestHostOperators(t *testing.T) {
+	i := NewInterpreter()
+	i.GlobalEnv.Define("price", money{1250})
+	i.GlobalEnv.Define("tax", money{175})
+	i.Interpret(mustParse(`
+var total = price + tax;
+var cheap = tax < price;
+var reversed = price > tax;
+var same = total == price + tax;
+var different = total != price;
+var double = price * 2;
+var triple = 3 * price;
+`))
+
+	if got := Stringify(i.GlobalEnv.Get("total")); got != "$14.25" {
+		t.Errorf("got total %s, expected $14.25", got)
+	}
+	if got := Stringify(i.GlobalEnv.Get("double")); got != "$25.00" {
+		t.Errorf("got double %s, expected $25.00", got)
+	}
+	if got := Stringify(i.GlobalEnv.Get("triple")); got != "$37.50" {
+		t.Errorf("got triple %s, expected $37.50", got)
+	}
+	for _, name := range []string{"cheap", "reversed", "same", "different"} {
+		if got := i.GlobalEnv.Get(name); got != true {
+			t.Errorf("expected %s to be true, got %v", name, got)
+		}
+	}
+}
+
+// TestHostOperatorError ...
+func TestHostOperatorError(t *testing.T) {
+	defer func() {
+		re, ok := recover().(*RuntimeError)
+		if !ok || re.Line != 3 || re.Msg != "Can only add money to money." {
+			t.Errorf("expected a runtime error on line 3, got %v", re)
+		}
+	}()
+	expr := &ExprBinary{
+		Left:     &ExprLiteral{Value: money{100}},
+		Operator: Token{Type: TokenTypePlus, Lexeme: "+", Line: 3},
+		Right:    &ExprLiteral{Value: 1.0},
+	}
+	NewInterpreter().evaluate(expr)
+}
+
+// TestOperandErrors checks that operands of the wrong type raise runtime
+// errors at the operator in every engine.
+func TestOperandErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		msg    string
+	}{
+		{`"a" - 1;`, "Operands must be numbers."},
+		{`1 < "2";`, "Operands must be numbers."},
+		{`nil * 2;`, "Operands must be numbers."},
+		{`-"x";`, "Operand must be a number."},
+		{`nil + 1;`, "Operands must be two numbers or two strings."},
+		{`true + clock;`, "Operands must be two numbers or two strings."},
+	}
+	for _, test := range tests {
+		stmts, err := Parse("print 0;\n" + test.source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, run := range engines(stmts) {
+			ds := run(NewInterpreter(WithStdout(&bytes.Buffer{}), WithStderr(&bytes.Buffer{})))
+			if len(ds) != 1 || ds[0].Message != test.msg || ds[0].Span.Line != 2 {
+				t.Errorf("%s: %s got %v, expected %q on line 2", name, test.source, ds, test.msg)
+			}
+		}
+	}
+}
+
+// TestEquality ...
+func TestEquality(t *testing.T) {
+	stdout := bytes.Buffer{}
+	i := NewInterpreter(WithStdout(&stdout))
+	i.Define("price", money{100})
+	stmts, err := Parse(`
+print nil == nil;
+print nil == false;
+print 0 == false;
+print 1 == "1";
+print "a" == "a";
+print "a" != "b";
+print true == true;
+print clock == clock;
+print clock == iter;
+print price == nil;
+print !"x";
+print !nil;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds := i.Interpret(stmts); len(ds) > 0 {
+		t.Fatal(ds)
+	}
+	expected := "true\nfalse\nfalse\nfalse\ntrue\ntrue\ntrue\ntrue\nfalse\nfalse\nfalse\ntrue\n"
+	if got := stdout.String(); got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
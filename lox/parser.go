@@ -4,22 +4,31 @@ import (
 	"fmt"
 )
 
-var currentParserPointer int
-
 // Parser ...
 type Parser struct {
-	tokens []*Token
+	tokens  []*Token
+	current int
+	errors  []error
+	dialect Dialect
+	// functions counts the function bodies being parsed.
+	functions int
 }
 
 // NewParser ...
 func NewParser(tokens []*Token) *Parser {
 	np := new(Parser)
 	np.tokens = tokens
-	currentParserPointer = 0
+	np.current = 0
 	return np
 }
 
-func (p Parser) match(types ...TokenType) bool {
+// SetDialect sets the dialect the parser accepts; DialectStrict rejects the
+// range operators.
+func (p *Parser) SetDialect(d Dialect) {
+	p.dialect = d
+}
+
+func (p *Parser) match(types ...TokenType) bool {
 	for _, tokenType := range types {
 		if p.check(tokenType) {
 			p.advance()
@@ -29,115 +38,153 @@ func (p Parser) match(types ...TokenType) bool {
 	return false
 }
 
-func (p Parser) check(tokenType TokenType) bool {
+func (p *Parser) check(tokenType TokenType) bool {
 	if p.isAtEnd() {
 		return false
 	}
 	return p.peek().Type == tokenType
 }
 
-func (p Parser) advance() *Token {
+func (p *Parser) advance() *Token {
 	if !p.isAtEnd() {
-		currentParserPointer++
+		p.current++
 	}
 	return p.previous()
 }
 
-func (p Parser) isAtEnd() bool {
+func (p *Parser) isAtEnd() bool {
 	return p.peek().Type == TokenTypeEOF
 }
 
-func (p Parser) peek() Token {
-	return *p.tokens[currentParserPointer]
+func (p *Parser) peek() Token {
+	return *p.tokens[p.current]
 }
 
-func (p Parser) previous() *Token {
-	return p.tokens[currentParserPointer-1]
+func (p *Parser) previous() *Token {
+	return p.tokens[p.current-1]
 }
 
 // Parse ...
-func (p Parser) Parse() []Stmt {
+func (p *Parser) Parse() []Stmt {
 	statements := make([]Stmt, 0)
 	for {
 		if p.isAtEnd() {
 			break
 		}
-		statements = append(statements, p.declaration())
+		statement := p.declaration()
+		if statement == nil {
+			continue
+		}
+		if yield := findYield([]Stmt{statement}); yield != nil {
+			p.parseErr(yield.Keyword, "Can't yield outside of a function.")
+		}
+		statements = append(statements, statement)
 	}
 	return statements
 }
 
-func (p Parser) declaration() Stmt {
+// declaration parses a declaration or statement. On a syntax error it
+// synchronizes and returns nil, so that parsing carries on and reports the
+// errors in later statements too.
+func (p *Parser) declaration() (stmt Stmt) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*ParseError); !ok {
+				panic(r)
+			}
+			p.synchronize()
+			stmt = nil
+		}
+	}()
+	start := p.current
+	stmt = p.declarationAt()
+	p.span(stmt, start)
+	return stmt
+}
+
+func (p *Parser) declarationAt() Stmt {
 	if p.match(TokenTypeFun) {
 		return p.function("function")
 	}
+	if p.match(TokenTypeAsync) {
+		p.consume(TokenTypeFun, "Expect 'fun' after 'async'.")
+		return p.asyncFunction()
+	}
 	if p.match(TokenTypeVar) {
 		return p.varDeclaration()
 	}
 	return p.statement()
 }
 
-func (p Parser) function(kind string) Stmt {
+func (p *Parser) function(kind string) Stmt {
 	name := p.consume(TokenTypeIdentifier, fmt.Sprintf("Expect %s name.", kind))
 	p.consume(TokenTypeLeftParen, fmt.Sprintf("Expect '(' after %s name.", kind))
 	params := make([]Token, 0)
 	if !p.check(TokenTypeRightParen) {
 		for {
-			params = append(params, *p.consume(TokenTypeIdentifier, "Expect ')' after parameters."))
+			params = append(params, *p.consume(TokenTypeIdentifier, "Expect parameter name."))
 			if !p.match(TokenTypeComma) {
 				break
 			}
 		}
 	}
-	p.consume(TokenTypeRightParen, "Expect ')' after parameters")
+	p.consume(TokenTypeRightParen, "Expect ')' after parameters.")
 	p.consume(TokenTypeLeftBrace, fmt.Sprintf("Expect '{' before %s body.", kind))
+	p.functions++
+	defer func() { p.functions-- }()
 	body := p.block()
 	return NewFunctionStmt(*name, params, body)
 }
 
-func (p Parser) varDeclaration() Stmt {
-	name := p.consume(TokenTypeIdentifier, "Expect variable name")
+func (p *Parser) asyncFunction() Stmt {
+	stmt := p.function("function").(*FunctionStmt)
+	if yield := findYield(stmt.Body); yield != nil {
+		p.parseErr(yield.Keyword, "Can't yield inside an async function.")
+	}
+	stmt.IsGenerator = false
+	stmt.IsAsync = true
+	return stmt
+}
+
+func (p *Parser) varDeclaration() Stmt {
+	name := p.consume(TokenTypeIdentifier, "Expect variable name.")
 	var initializer Expr
 	if p.match(TokenTypeEqual) {
-		initializer, _ = p.expression()
+		initializer = p.expression()
 	}
-	p.consume(TokenTypeSemiColon, "Expect ';' after variable declaration")
+	p.consume(TokenTypeSemiColon, "Expect ';' after variable declaration.")
 	return NewVarStmt(*name, initializer)
 }
 
-func (p Parser) expression() (Expr, error) {
+func (p *Parser) expression() Expr {
 	return p.assignment()
 }
 
-func (p Parser) assignment() (Expr, error) {
-	expr, e := p.or()
-	if e != nil {
-		return nil, e
-	}
+func (p *Parser) assignment() Expr {
+	start := p.current
+	expr := p.or()
 
 	if p.match(TokenTypeEqual) {
 		equals := p.previous()
-		value, e := p.assignment()
-		if e != nil {
-			return nil, e
-		}
+		value := p.assignment()
 		token, ok := expr.(*ExprVar)
 		if ok {
 			name := token.Name
-			return &ExprAssign{Name: name, Value: value}, nil
+			return p.finish(&ExprAssign{Name: name, Value: value}, start)
+		}
+		if get, ok := expr.(*ExprGet); ok {
+			return p.finish(&ExprSet{Object: get.Object, Name: get.Name, Value: value}, start)
 		}
-		e = &VarError{Name: equals.Lexeme, Msg: "Invalid assignment target"}
-		fmt.Println(e)
-		return nil, e
+		// The target is reported but not thrown: the parser is not
+		// confused, so there is no need to synchronize.
+		p.parseErr(*equals, "Invalid assignment target.")
 	}
-	return expr, nil
+	return expr
 }
 
-func (p Parser) or() (Expr, error) {
-	expr, e := p.and()
-	if e != nil {
-		return nil, e
-	}
+func (p *Parser) or() Expr {
+	start := p.current
+	expr := p.and()
 
 	for {
 		if !p.match(TokenTypeOr) {
@@ -145,36 +192,37 @@ func (p Parser) or() (Expr, error) {
 		}
 
 		operator := p.previous()
-		right, e := p.and()
-		if e != nil {
-			return nil, e
-		}
+		right := p.and()
 		expr = &ExprLogical{Left: expr, Operator: *operator, Right: right}
+		p.span(expr, start)
 	}
-	return expr, nil
+	return expr
 }
 
-func (p Parser) and() (Expr, error) {
-	expr, e := p.equality()
-	if e != nil {
-		return nil, e
-	}
+func (p *Parser) and() Expr {
+	start := p.current
+	expr := p.equality()
 
 	for {
 		if !p.match(TokenTypeAnd) {
 			break
 		}
 		operator := p.previous()
-		right, e := p.equality()
-		if e != nil {
-			return nil, e
-		}
+		right := p.equality()
 		expr = &ExprLogical{Left: expr, Operator: *operator, Right: right}
+		p.span(expr, start)
 	}
-	return expr, nil
+	return expr
+}
+
+func (p *Parser) statement() Stmt {
+	start := p.current
+	stmt := p.statementAt()
+	p.span(stmt, start)
+	return stmt
 }
 
-func (p Parser) statement() Stmt {
+func (p *Parser) statementAt() Stmt {
 	if p.match(TokenTypeFor) {
 		return p.forStatement()
 	}
@@ -187,6 +235,15 @@ func (p Parser) statement() Stmt {
 	if p.match(TokenTypeReturn) {
 		return p.returnStatement()
 	}
+	if p.match(TokenTypeYield) {
+		return p.yieldStatement()
+	}
+	if p.match(TokenTypeSpawn) {
+		return p.spawnStatement()
+	}
+	if p.match(TokenTypeSelect) {
+		return p.selectStatement()
+	}
 	if p.match(TokenTypeWhile) {
 		return p.whileStatement()
 	}
@@ -196,37 +253,112 @@ func (p Parser) statement() Stmt {
 	return p.expressionStatement()
 }
 
-func (p Parser) returnStatement() Stmt {
+func (p *Parser) returnStatement() Stmt {
 	keyword := p.previous()
+	if p.functions == 0 {
+		p.parseErr(*keyword, "Can't return from top-level code.")
+	}
 	var value Expr
-	var e error
 	if !p.check(TokenTypeSemiColon) {
-		value, e = p.expression()
-		if e != nil {
-			fmt.Println("Error while getting expression for the return statement.")
-			return nil
-		}
+		value = p.expression()
 	}
 	p.consume(TokenTypeSemiColon, "Expect ';' after return value.")
 	return NewReturnStmt(*keyword, value)
 }
 
-func (p Parser) block() []Stmt {
+func (p *Parser) yieldStatement() Stmt {
+	keyword := p.previous()
+	var value Expr
+	if !p.check(TokenTypeSemiColon) {
+		value = p.expression()
+	}
+	p.consume(TokenTypeSemiColon, "Expect ';' after yield value.")
+	return NewYieldStmt(*keyword, value)
+}
+
+func (p *Parser) spawnStatement() Stmt {
+	keyword := p.previous()
+	expr := p.expression()
+	call, ok := expr.(*ExprCall)
+	if !ok {
+		panic(p.parseErr(*keyword, "Expect function call after 'spawn'."))
+	}
+	p.consume(TokenTypeSemiColon, "Expect ';' after spawn call.")
+	return NewSpawnStmt(*keyword, call)
+}
+
+func (p *Parser) selectStatement() Stmt {
+	keyword := p.previous()
+	p.consume(TokenTypeLeftBrace, "Expect '{' after 'select'.")
+	cases := make([]*SelectCase, 0)
+	var defaultBody []Stmt
+	for {
+		if p.check(TokenTypeRightBrace) || p.isAtEnd() {
+			break
+		}
+		if p.match(TokenTypeDefault) {
+			if defaultBody != nil {
+				p.parseErr(*p.previous(), "Select can have only one default clause.")
+			}
+			p.consume(TokenTypeLeftBrace, "Expect '{' after 'default'.")
+			defaultBody = p.block()
+			continue
+		}
+		p.consume(TokenTypeCase, "Expect 'case' or 'default' in select.")
+		cases = append(cases, p.selectCase())
+	}
+	p.consume(TokenTypeRightBrace, "Expect '}' after select cases.")
+	return NewSelectStmt(*keyword, cases, defaultBody)
+}
+
+// selectCase parses "recv(ch)", "var name = recv(ch)" or "send(ch, value)",
+// followed by the case body.
+func (p *Parser) selectCase() *SelectCase {
+	var name *Token
+	if p.match(TokenTypeVar) {
+		name = p.consume(TokenTypeIdentifier, "Expect variable name.")
+		p.consume(TokenTypeEqual, "Expect '=' after variable name.")
+	}
+	operation := p.consume(TokenTypeIdentifier, "Expect 'send' or 'recv' after 'case'.")
+	if operation.Lexeme != "send" && operation.Lexeme != "recv" {
+		panic(p.parseErr(*operation, "Expect 'send' or 'recv' after 'case'."))
+	}
+	if name != nil && operation.Lexeme == "send" {
+		panic(p.parseErr(*operation, "Can only bind the value of 'recv'."))
+	}
+	p.consume(TokenTypeLeftParen, fmt.Sprintf("Expect '(' after '%s'.", operation.Lexeme))
+	channel := p.expression()
+	var value Expr
+	if operation.Lexeme == "send" {
+		p.consume(TokenTypeComma, "Expect ',' after channel.")
+		value = p.expression()
+	}
+	p.consume(TokenTypeRightParen, "Expect ')' after select case.")
+	p.consume(TokenTypeLeftBrace, "Expect '{' before case body.")
+	body := p.block()
+	return &SelectCase{Operation: *operation, Name: name, Channel: channel, Value: value, Body: body}
+}
+
+// block parses the statements after a '{' up to the matching '}'.
+func (p *Parser) block() []Stmt {
+	open := p.previous()
 	statements := make([]Stmt, 0)
 	for {
 		if p.check(TokenTypeRightBrace) || p.isAtEnd() {
 			break
 		}
-		statements = append(statements, p.declaration())
+		if statement := p.declaration(); statement != nil {
+			statements = append(statements, statement)
+		}
 	}
-	p.consume(TokenTypeRightBrace, "Expect '}' after block.")
+	p.consumeClosing(TokenTypeRightBrace, "Expect '}' after block.", open)
 	return statements
 }
 
-func (p Parser) ifStatement() Stmt {
+func (p *Parser) ifStatement() Stmt {
 	p.consume(TokenTypeLeftParen, "Expect '(' after 'if'.")
-	condition, _ := p.expression()
-	p.consume(TokenTypeRightParen, "Expect ')' after if condition")
+	condition := p.expression()
+	p.consume(TokenTypeRightParen, "Expect ')' after if condition.")
 	thenBranch := p.statement()
 	var elseBranch Stmt
 	if p.match(TokenTypeElse) {
@@ -235,14 +367,15 @@ func (p Parser) ifStatement() Stmt {
 	return NewIfStmt(condition, thenBranch, elseBranch)
 }
 
-func (p Parser) printStatement() Stmt {
-	value, _ := p.expression()
+func (p *Parser) printStatement() Stmt {
+	value := p.expression()
 	p.consume(TokenTypeSemiColon, "Expect ';' after value.")
 	return NewPrintStmt(value)
 }
 
-func (p Parser) forStatement() Stmt {
-	p.consume(TokenTypeLeftParen, "Expect '(' after for")
+func (p *Parser) forStatement() Stmt {
+	start := p.current - 1
+	p.consume(TokenTypeLeftParen, "Expect '(' after 'for'.")
 	var initializer Stmt
 	if p.match(TokenTypeSemiColon) {
 		initializer = nil
@@ -254,29 +387,35 @@ func (p Parser) forStatement() Stmt {
 
 	var condition Expr
 	if !p.check(TokenTypeSemiColon) {
-		condition, _ = p.expression()
+		condition = p.expression()
 	}
-	p.consume(TokenTypeSemiColon, "Expect ';' after loop condition")
+	p.consume(TokenTypeSemiColon, "Expect ';' after loop condition.")
 
 	var increment Expr
 
 	if !p.check(TokenTypeRightParen) {
-		increment, _ = p.expression()
+		increment = p.expression()
 	}
-	p.consume(TokenTypeRightParen, "Expect ')' after for clauses")
+	p.consume(TokenTypeRightParen, "Expect ')' after for clauses.")
 	body := p.statement()
 
+	// The statements the loop is desugared into span the whole loop.
 	if increment != nil {
 		statements := make([]Stmt, 0)
-		statements = append(statements, body, NewExpressionStmt(increment))
+		incrementStmt := NewExpressionStmt(increment)
+		incrementStmt.(spanSetter).setSpan(increment.Span())
+		statements = append(statements, body, incrementStmt)
 		body = NewBlockStmt(statements)
+		p.span(body, start)
 	}
 
 	if condition == nil {
-		condition = &ExprLiteral{true}
+		condition = &ExprLiteral{Value: true}
+		condition.(spanSetter).setSpan(p.tokens[start].Span())
 	}
 
 	body = NewWhileStmt(condition, body)
+	p.span(body, start)
 
 	if initializer != nil {
 		statements := make([]Stmt, 0)
@@ -286,188 +425,232 @@ func (p Parser) forStatement() Stmt {
 	return body
 }
 
-func (p Parser) whileStatement() Stmt {
-	p.consume(TokenTypeLeftParen, "Expect '(' after while.")
-	condition, _ := p.expression()
-	p.consume(TokenTypeRightParen, "Expect ')' after condition")
+func (p *Parser) whileStatement() Stmt {
+	p.consume(TokenTypeLeftParen, "Expect '(' after 'while'.")
+	condition := p.expression()
+	p.consume(TokenTypeRightParen, "Expect ')' after condition.")
 	body := p.statement()
 	return NewWhileStmt(condition, body)
 }
 
-func (p Parser) expressionStatement() Stmt {
-	expr, _ := p.expression()
+func (p *Parser) expressionStatement() Stmt {
+	expr := p.expression()
 	p.consume(TokenTypeSemiColon, "Expect ';' after expression.")
 	return NewExpressionStmt(expr)
 }
 
-func (p Parser) equality() (Expr, error) {
-	expr, e := p.comparison()
-	if e != nil {
-		return nil, e
-	}
+func (p *Parser) equality() Expr {
+	start := p.current
+	expr := p.comparison()
 	for {
 		if !p.match(TokenTypeBangEqual, TokenTypeEqualEqual) {
 			break
 		}
 		operator := p.previous()
-		right, e := p.comparison()
-		if e != nil {
-			return nil, e
-		}
+		right := p.comparison()
 		expr = &ExprBinary{Left: expr, Operator: *operator, Right: right}
+		p.span(expr, start)
 	}
-	return expr, nil
+	return expr
 }
 
-func (p Parser) comparison() (Expr, error) {
-	expr, e := p.addition()
-	if e != nil {
-		return nil, e
-	}
+func (p *Parser) comparison() Expr {
+	start := p.current
+	expr := p.rangeExpr()
 	for {
 		if !p.match(TokenTypeGreater, TokenTypeGreaterEqual, TokenTypeLess, TokenTypeLessEqual) {
 			break
 		}
 		operator := p.previous()
-		right, e := p.addition()
-		if e != nil {
-			return nil, e
-		}
+		right := p.rangeExpr()
 		expr = &ExprBinary{Left: expr, Operator: *operator, Right: right}
+		p.span(expr, start)
 	}
-	return expr, nil
+	return expr
 }
 
-func (p Parser) addition() (Expr, error) {
-	expr, e := p.multiplication()
-	if e != nil {
-		return nil, e
+// rangeExpr is not associative: "1..2..3" is a syntax error.
+func (p *Parser) rangeExpr() Expr {
+	start := p.current
+	expr := p.addition()
+	if p.match(TokenTypeDotDot, TokenTypeDotDotEqual) {
+		operator := p.previous()
+		if p.dialect == DialectStrict {
+			panic(p.parseErr(*operator, "Ranges are not part of strict Lox."))
+		}
+		right := p.addition()
+		expr = &ExprBinary{Left: expr, Operator: *operator, Right: right}
+		p.span(expr, start)
 	}
+	return expr
+}
+
+func (p *Parser) addition() Expr {
+	start := p.current
+	expr := p.multiplication()
 	for {
 		if !p.match(TokenTypeMinus, TokenTypePlus) {
 			break
 		}
 		operator := p.previous()
-		right, e := p.multiplication()
-		if e != nil {
-			return nil, e
-		}
+		right := p.multiplication()
 		expr = &ExprBinary{Left: expr, Operator: *operator, Right: right}
+		p.span(expr, start)
 	}
-	return expr, nil
+	return expr
 }
 
-func (p Parser) multiplication() (Expr, error) {
-	expr, e := p.unary()
-	if e != nil {
-		return nil, e
-	}
+func (p *Parser) multiplication() Expr {
+	start := p.current
+	expr := p.unary()
 	for {
 		if !p.match(TokenTypeSlash, TokenTypeStar) {
 			break
 		}
 
 		operator := p.previous()
-		right, e := p.unary()
-		if e != nil {
-			return nil, e
-		}
+		right := p.unary()
 		expr = &ExprBinary{Left: expr, Operator: *operator, Right: right}
+		p.span(expr, start)
 	}
-	return expr, nil
+	return expr
 }
 
-func (p Parser) unary() (Expr, error) {
+func (p *Parser) unary() Expr {
+	start := p.current
+	if p.match(TokenTypeAwait) {
+		keyword := p.previous()
+		value := p.unary()
+		return p.finish(&ExprAwait{Keyword: *keyword, Value: value}, start)
+	}
 	if p.match(TokenTypeBang, TokenTypeMinus) {
 		operator := p.previous()
-		right, e := p.unary()
-		if e != nil {
-			return nil, e
-		}
-		return &ExprUnary{Operator: *operator, Right: right}, nil
+		right := p.unary()
+		return p.finish(&ExprUnary{Operator: *operator, Right: right}, start)
 	}
 	return p.call()
 }
 
-func (p Parser) call() (Expr, error) {
-	expr, e := p.primary()
-	if e != nil {
-		return nil, e
-	}
+func (p *Parser) call() Expr {
+	start := p.current
+	expr := p.primary()
 	for {
 		if p.match(TokenTypeLeftParen) {
 			expr = p.finishCall(expr)
+			p.span(expr, start)
+		} else if p.match(TokenTypeDot) {
+			name := p.consume(TokenTypeIdentifier, "Expect property name after '.'.")
+			expr = &ExprGet{Object: expr, Name: *name}
+			p.span(expr, start)
 		} else {
 			break
 		}
 	}
-	return expr, nil
+	return expr
 }
 
-func (p Parser) finishCall(callee Expr) Expr {
+func (p *Parser) finishCall(callee Expr) Expr {
+	open := p.previous()
 	arguments := make([]*Expr, 0)
 	if !p.check(TokenTypeRightParen) {
 		for {
-			thisExpr, e := p.expression()
-			if e != nil {
-				fmt.Println(e)
-				return nil
-			}
+			thisExpr := p.expression()
 			arguments = append(arguments, &thisExpr)
 			if !p.match(TokenTypeComma) {
 				break
 			}
 		}
 	}
-	paren := p.consume(TokenTypeRightParen, "Expect ')' after arguments")
+	paren := p.consumeClosing(TokenTypeRightParen, "Expect ')' after arguments.", open)
 	return &ExprCall{Callee: callee, Paren: *paren, Arguments: arguments}
 }
 
-func (p Parser) primary() (Expr, error) {
+func (p *Parser) primary() Expr {
+	start := p.current
 	if p.match(TokenTypeFalse) {
-		return &ExprLiteral{false}, nil
+		return p.finish(&ExprLiteral{Value: false}, start)
 	}
 
 	if p.match(TokenTypeTrue) {
-		return &ExprLiteral{true}, nil
+		return p.finish(&ExprLiteral{Value: true}, start)
 	}
 
 	if p.match(TokenTypeNil) {
-		return &ExprLiteral{nil}, nil
+		return p.finish(&ExprLiteral{Value: nil}, start)
 	}
 
 	if p.match(TokenTypeNumber, TokenTypeString) {
-		return &ExprLiteral{p.previous().Literal}, nil
+		return p.finish(&ExprLiteral{Value: p.previous().Literal}, start)
 	}
 	if p.match(TokenTypeIdentifier) {
-		return &ExprVar{*p.previous()}, nil
+		return p.finish(&ExprVar{Name: *p.previous()}, start)
 	}
 	if p.match(TokenTypeLeftParen) {
-		expr, err := p.expression()
-		if err != nil {
-			return nil, err
-		}
-		p.consume(TokenTypeRightParen, "Expect ')' after expression.")
-		return &ExprGrouping{expr}, nil
+		open := p.previous()
+		expr := p.expression()
+		p.consumeClosing(TokenTypeRightParen, "Expect ')' after expression.", open)
+		return p.finish(&ExprGrouping{Expr: expr}, start)
 	}
-	return nil, p.parseErr(p.peek(), "Expect expression.")
+	panic(p.parseErr(p.peek(), "Expect expression."))
 }
 
-func (p Parser) consume(tokenType TokenType, message string) *Token {
+func (p *Parser) consume(tokenType TokenType, message string) *Token {
 	if !p.check(tokenType) {
-		fmt.Println(p.parseErr(p.peek(), message))
-		return nil
+		panic(p.parseErr(p.peek(), message))
 	}
 	return p.advance()
 }
 
-func (p Parser) parseErr(t Token, message string) error {
-	return &ParseError{Token: t, Msg: message}
+// span records on n that it was parsed from the tokens from start up to
+// the last one consumed.
+func (p *Parser) span(n interface{}, start int) {
+	node, ok := n.(spanSetter)
+	if !ok || p.current <= start {
+		return
+	}
+	node.setSpan(p.tokens[start].Span().To(p.previous().Span()))
 }
 
-// Synchronize
+// finish records the span of expr and returns it.
+func (p *Parser) finish(expr Expr, start int) Expr {
+	p.span(expr, start)
+	return expr
+}
+
+// consumeClosing is consume for the delimiter closing open. Its error
+// points at open as well.
+func (p *Parser) consumeClosing(tokenType TokenType, message string, open *Token) *Token {
+	if p.check(tokenType) {
+		return p.advance()
+	}
+	e := &ParseError{Token: p.peek(), Msg: message}
+	if open != nil && (open.Type == TokenTypeLeftBrace || open.Type == TokenTypeLeftParen) {
+		e.Notes = []Note{{Message: fmt.Sprintf("to match this '%s'", open.Lexeme), Span: open.Span()}}
+	}
+	p.errors = append(p.errors, e)
+	panic(e)
+}
+
+// Errors returns the parse errors found by Parse.
+func (p *Parser) Errors() []error {
+	return p.errors
+}
+
+// Diagnostics returns the parse errors found by Parse as diagnostics.
+func (p *Parser) Diagnostics() Diagnostics {
+	return ErrorList(p.errors).Diagnostics()
+}
+
+// parseErr records a parse error and returns it.
+func (p *Parser) parseErr(t Token, message string) error {
+	e := &ParseError{Token: t, Msg: message}
+	p.errors = append(p.errors, e)
+	return e
+}
 
-func (p Parser) synchronize() {
+// synchronize discards tokens up to the likely start of the next
+// statement, so that an error does not cascade into spurious ones.
+func (p *Parser) synchronize() {
 	p.advance()
 	for {
 		if p.isAtEnd() {
@@ -477,7 +660,8 @@ func (p Parser) synchronize() {
 			return
 		}
 		switch p.peek().Type {
-		case TokenTypeClass, TokenTypeFun, TokenTypeVar, TokenTypeFor, TokenTypeIf, TokenTypeWhile, TokenTypePrint, TokenTypeReturn:
+		case TokenTypeClass, TokenTypeFun, TokenTypeVar, TokenTypeFor, TokenTypeIf, TokenTypeWhile, TokenTypePrint, TokenTypeReturn,
+			TokenTypeAsync, TokenTypeSpawn, TokenTypeSelect, TokenTypeYield:
 			return
 		}
 		p.advance()
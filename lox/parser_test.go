@@ -0,0 +1,154 @@
+package lox
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentScanning runs many scanners at once; run with -race.
+func TestConcurrentScanning(t *testing.T) {
+	var wg sync.WaitGroup
+	for n := 0; n < 16; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			source := fmt.Sprintf("var v%d = %d;\nprint v%d + 1;", n, n, n)
+			tokens := NewScanner(source).ScanTokens()
+			if len(tokens) != 11 {
+				t.Errorf("scanner %d: got %d tokens, expected 11", n, len(tokens))
+				return
+			}
+			if got := tokens[1].Lexeme; got != fmt.Sprintf("v%d", n) {
+				t.Errorf("scanner %d: got identifier %s", n, got)
+			}
+			if got := tokens[len(tokens)-1].Line; got != 2 {
+				t.Errorf("scanner %d: got EOF on line %d, expected 2", n, got)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+// TestConcurrentInterpreters scans, parses and runs a program per goroutine;
+// run with -race.
+func TestConcurrentInterpreters(t *testing.T) {
+	var wg sync.WaitGroup
+	for n := 0; n < 16; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			i := interpret(fmt.Sprintf(`
+fun sum(n) {
+  var total = 0;
+  for (var i = 1; i <= n; i = i + 1) total = total + i;
+  return total;
+}
+var result = sum(%d);
+`, n))
+			if got, expected := i.GlobalEnv.Get("result"), float64(n*(n+1)/2); got != expected {
+				t.Errorf("interpreter %d: got %v, expected %v", n, got, expected)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+// TestSharedProgram runs one parsed program in several interpreters at
+// once, which must only read the statements; run with -race.
+func TestSharedProgram(t *testing.T) {
+	stmts := mustParse(scopesProgram)
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			i := NewInterpreter()
+			if ds := i.Interpret(stmts); len(ds) > 0 {
+				t.Errorf("interpreter %d: %v", n, ds)
+				return
+			}
+			if got := i.GlobalEnv.Get("result"); got != "globalglobal block inner" {
+				t.Errorf("interpreter %d: got %v, expected globalglobal block inner", n, got)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+// TestSpans ...
+func TestSpans(t *testing.T) {
+	source := "var s = \"a\nb\";\nprint s +  (1 * 2);"
+	stmts, err := ParseFile("spans.lox", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := func(span Span) string { return source[span.Start:span.End] }
+
+	print := stmts[1].(*PrintStmt)
+	sum := print.Expression.(*ExprBinary)
+	tests := []struct {
+		span   Span
+		text   string
+		line   int
+		column int
+	}{
+		{stmts[0].Span(), "var s = \"a\nb\";", 1, 1},
+		{print.Span(), "print s +  (1 * 2);", 3, 1},
+		{sum.Span(), "s +  (1 * 2)", 3, 7},
+		{sum.Operator.Span(), "+", 3, 9},
+		{sum.Right.Span(), "(1 * 2)", 3, 12},
+		{sum.Right.(*ExprGrouping).Expr.Span(), "1 * 2", 3, 13},
+	}
+	for _, test := range tests {
+		if got := text(test.span); got != test.text || test.span.Line != test.line || test.span.Column != test.column || test.span.File != "spans.lox" {
+			t.Errorf("got %q at %d:%d in %q, expected %q at %d:%d", got, test.span.Line, test.span.Column, test.span.File, test.text, test.line, test.column)
+		}
+	}
+
+	_, err = ParseFile("spans.lox", "print 1;\n  print ;")
+	if d := err.(ErrorList).Diagnostics()[0]; d.Span.Line != 2 || d.Span.Column != 9 || d.Span.File != "spans.lox" {
+		t.Errorf("got %+v, expected the error at 2:9", d.Span)
+	}
+}
+
+// TestErrorRecovery checks that the parser reports every independent syntax
+// error and keeps the statements between them.
+func TestErrorRecovery(t *testing.T) {
+	source := "var ;\nprint 1;\nprint (2;\n{ var x = ; print 3; }\na + = 1;\nprint 4;"
+	p := NewParser(NewScanner(source).ScanTokens())
+	statements := p.Parse()
+
+	expected := []string{
+		"[line 1] Error at ';': Expect variable name.",
+		"[line 3] Error at ';': Expect ')' after expression.",
+		"[line 4] Error at ';': Expect expression.",
+		"[line 5] Error at '=': Expect expression.",
+	}
+	errs := p.Errors()
+	if len(errs) != len(expected) {
+		t.Fatalf("got %d errors, expected %d: %v", len(errs), len(expected), errs)
+	}
+	for idx, err := range errs {
+		if err.Error() != expected[idx]+"\n" {
+			t.Errorf("error %d: got %q, expected %q", idx, err.Error(), expected[idx])
+		}
+	}
+	// print 1, the block with print 3, and print 4.
+	if len(statements) != 3 {
+		t.Errorf("got %d statements, expected 3", len(statements))
+	}
+}
+
+// TestTopLevelReturn checks that a return outside of a function is a parse
+// error in every dialect, for every engine to reject alike.
+func TestTopLevelReturn(t *testing.T) {
+	source := "fun f() { return 1; }\n{\n  return;\n}\n"
+	expected := "[line 3] Error at 'return': Can't return from top-level code.\n"
+	for _, dialect := range []Dialect{DialectExtended, DialectStrict} {
+		_, err := NewInterpreter(WithDialect(dialect)).Parse("", source)
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s: got %v, expected %q", dialect, err, expected)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package lox
+
+import (
+	"fmt"
+)
+
+type promiseState int
+
+const (
+	promisePending promiseState = iota
+	promiseFulfilled
+	promiseRejected
+)
+
+// Promise is the future returned by async functions and sleep(). It is
+// settled once, and its callbacks run as microtasks on the event loop.
+type Promise struct {
+	loop      *EventLoop
+	state     promiseState
+	value     interface{}
+	err       *RuntimeError
+	handled   bool
+	callbacks []func()
+}
+
+// NewPromise ...
+func NewPromise(loop *EventLoop) *Promise {
+	return &Promise{loop: loop}
+}
+
+func (p *Promise) resolve(value interface{}) {
+	if p.state != promisePending {
+		return
+	}
+	if inner, ok := value.(*Promise); ok {
+		inner.then(func() {
+			if inner.state == promiseRejected {
+				p.reject(inner.err)
+			} else {
+				p.resolve(inner.value)
+			}
+		})
+		return
+	}
+	p.state = promiseFulfilled
+	p.value = value
+	p.settle()
+}
+
+func (p *Promise) reject(err *RuntimeError) {
+	if p.state != promisePending {
+		return
+	}
+	p.state = promiseRejected
+	p.err = err
+	if !p.handled {
+		p.loop.rejections = append(p.loop.rejections, p)
+	}
+	p.settle()
+}
+
+func (p *Promise) settle() {
+	for _, callback := range p.callbacks {
+		p.loop.enqueue(callback)
+	}
+	p.callbacks = nil
+}
+
+// then schedules callback once the promise settles and marks any rejection
+// as handled.
+func (p *Promise) then(callback func()) {
+	p.handled = true
+	if p.state == promisePending {
+		p.callbacks = append(p.callbacks, callback)
+		return
+	}
+	p.loop.enqueue(callback)
+}
+
+// String ...
+func (p *Promise) String() string {
+	switch p.state {
+	case promiseFulfilled:
+		return fmt.Sprintf("<promise fulfilled %s>", Stringify(p.value))
+	case promiseRejected:
+		return "<promise rejected>"
+	default:
+		return "<promise pending>"
+	}
+}
+
+// awaitResult is what a suspended async body is resumed with.
+type awaitResult struct {
+	value interface{}
+	err   *RuntimeError
+}
+
+// NewAsyncCall starts the body of an async function and returns the promise
+// of its result. The body runs synchronously up to its first await, and is
+// resumed by the event loop each time the awaited promise settles.
+func NewAsyncCall(i *Interpreter, declaration FunctionStmt, env *Environment) *Promise {
+	loop := i.mainLoop()
+	promise := NewPromise(loop)
+	ai := *i
+	co := newCoroutine(func(co *coroutine) interface{} {
+		ai.co = nil
+		ai.async = co
+		if rv, ok := ai.ExecuteBlock(declaration.Body, env).(*ReturnValue); ok {
+			return rv.Value
+		}
+		return nil
+	})
+
+	var step func(resumed interface{})
+	step = func(resumed interface{}) {
+		value, running, err := resumeAsync(co, resumed)
+		switch {
+		case err != nil:
+			promise.reject(err)
+		case !running:
+			promise.resolve(value)
+		default:
+			awaited := value.(*Promise)
+			awaited.then(func() {
+				step(awaitResult{value: awaited.value, err: awaited.err})
+			})
+		}
+	}
+	step(nil)
+	return promise
+}
+
+// resumeAsync resumes an async body, turning a runtime error raised by it
+// into a rejection.
+func resumeAsync(co *coroutine, resumed interface{}) (value interface{}, running bool, err *RuntimeError) {
+	defer func() {
+		if r := recover(); r != nil {
+			re, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			err = re
+		}
+	}()
+	value, running = co.resume(resumed)
+	return value, running, nil
+}
@@ -0,0 +1,103 @@
+package lox
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// asyncProgram awaits timers in nested async functions.
+const asyncProgram = `
+var log = "";
+async fun fetch(name, ms) {
+  await sleep(ms);
+  log = log + name;
+  return name;
+}
+async fun both() {
+  var slow = fetch("slow ", 20);
+  var fast = fetch("fast ", 1);
+  return await slow + await fast;
+}
+var result = nil;
+async fun main() {
+  result = await both();
+}
+main();
+log = log + "sync ";
+`
+
+// TestAsyncAwait ...
+func TestAsyncAwait(t *testing.T) {
+	i := interpret(asyncProgram)
+	if got := i.GlobalEnv.Get("log"); got != "sync fast slow " {
+		t.Errorf("got log %q, expected %q", got, "sync fast slow ")
+	}
+	if got := i.GlobalEnv.Get("result"); got != "slow fast " {
+		t.Errorf("got result %q, expected %q", got, "slow fast ")
+	}
+}
+
+// TestTimers ...
+func TestTimers(t *testing.T) {
+	i := interpret(`
+var ticks = 0;
+var id = nil;
+fun tick() {
+  ticks = ticks + 1;
+  if (ticks > 2) clearInterval(id);
+}
+id = setInterval(tick, 1);
+var late = false;
+fun cancelled() { late = true; }
+clearTimeout(setTimeout(cancelled, 1));
+`)
+	if got := i.GlobalEnv.Get("ticks"); got != 3.0 {
+		t.Errorf("got %v ticks, expected 3", got)
+	}
+	if got := i.GlobalEnv.Get("late"); got != false {
+		t.Error("expected the cleared timeout not to fire")
+	}
+}
+
+// TestRejection checks that an unhandled rejection is reported at the
+// token that raised the error.
+func TestRejection(t *testing.T) {
+	i := NewInterpreter(WithStderr(&bytes.Buffer{}))
+	ds := i.Interpret(mustParse(`
+async fun fail() {
+  await sleep(1);
+  close(1);
+}
+var p = fail();
+`))
+	p := i.GlobalEnv.Get("p").(*Promise)
+	if p.state != promiseRejected || p.err.Line != 4 {
+		t.Errorf("expected a rejection from line 4, got %s", p)
+	}
+	if len(ds) != 1 || !strings.HasPrefix(ds[0].Message, "Unhandled promise rejection: ") || ds[0].Span.Line != 4 || ds[0].Span.Column != 10 {
+		t.Errorf("got %v, expected an unhandled rejection at the ')' of close(1)", ds)
+	}
+}
+
+// TestAwaitInPlainFunction checks that await in a plain function called
+// from an async one waits for the promise instead of suspending the async
+// function.
+func TestAwaitInPlainFunction(t *testing.T) {
+	i := interpret(`
+var log = "";
+async fun outer() {
+  fun wait() {
+    await sleep(1);
+    log = log + "waited ";
+  }
+  wait();
+  log = log + "outer ";
+}
+outer();
+log = log + "sync ";
+`)
+	if got := i.GlobalEnv.Get("log"); got != "waited outer sync " {
+		t.Errorf("got log %q, expected %q", got, "waited outer sync ")
+	}
+}
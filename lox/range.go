@@ -0,0 +1,134 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+)
+
+// Range is the lazy value produced by "a..b" and "a..=b". Elements are
+// computed from Start and Step on demand, so a range never allocates the
+// sequence it describes.
+type Range struct {
+	Start     float64
+	End       float64
+	Step      float64
+	Inclusive bool
+}
+
+// NewRange ...
+func NewRange(start float64, end float64, inclusive bool) *Range {
+	return &Range{Start: start, End: end, Step: 1, Inclusive: inclusive}
+}
+
+// rangeTolerance is how far, in steps, a number may be from an element of
+// a range and still be taken for it, so that rounding in steps such as 0.1
+// does not lose elements.
+const rangeTolerance = 1e-9
+
+// maxRangeSteps is how many steps a range may span: past 2^53 consecutive
+// elements stop being distinct numbers.
+const maxRangeSteps = 1 << 53
+
+// checkRange returns why start, end and step do not make a range, or ""
+// if they do.
+func checkRange(start float64, end float64, step float64) string {
+	if math.IsInf(start, 0) || math.IsNaN(start) || math.IsInf(end, 0) || math.IsNaN(end) {
+		return "Range bounds must be finite numbers."
+	}
+	if math.Abs((end-start)/step) > maxRangeSteps {
+		return "Range has too many elements."
+	}
+	return ""
+}
+
+// Len returns the number of elements in the range.
+func (r *Range) Len() int {
+	span := (r.End - r.Start) / r.Step
+	if nearest := math.Round(span); math.Abs(span-nearest) < rangeTolerance {
+		span = nearest
+	}
+	if span < 0 {
+		return 0
+	}
+	if r.Inclusive {
+		return int(math.Floor(span)) + 1
+	}
+	return int(math.Ceil(span))
+}
+
+// At returns the element at index idx, without bounds checks.
+func (r *Range) At(idx int) float64 {
+	return r.Start + float64(idx)*r.Step
+}
+
+// Contains reports whether value is one of the elements of the range.
+func (r *Range) Contains(value interface{}) bool {
+	n, ok := value.(float64)
+	if !ok {
+		return false
+	}
+	idx := math.Round((n - r.Start) / r.Step)
+	if idx < 0 || idx >= float64(r.Len()) {
+		return false
+	}
+	return math.Abs(r.At(int(idx))-n) <= math.Abs(r.Step)*rangeTolerance
+}
+
+// Iterator ...
+func (r *Range) Iterator() Iterator {
+	return &rangeIterator{r: r}
+}
+
+// String ...
+func (r *Range) String() string {
+	op := ".."
+	if r.Inclusive {
+		op = "..="
+	}
+	if r.Step != 1 {
+		return fmt.Sprintf("%s%s%s step %s", formatNumber(r.Start), op, formatNumber(r.End), formatNumber(r.Step))
+	}
+	return formatNumber(r.Start) + op + formatNumber(r.End)
+}
+
+type rangeIterator struct {
+	r   *Range
+	idx int
+}
+
+func (it *rangeIterator) HasNext() bool {
+	return it.idx < it.r.Len()
+}
+
+func (it *rangeIterator) Next() interface{} {
+	if !it.HasNext() {
+		return nil
+	}
+	value := it.r.At(it.idx)
+	it.idx++
+	return value
+}
+
+// Step ...
+type Step struct{}
+
+// Arity ...
+func (s Step) Arity() int {
+	return 2
+}
+
+// Call ...
+func (s Step) Call(i *Interpreter, args []interface{}) interface{} {
+	r, ok := args[0].(*Range)
+	if !ok {
+		panic(&RuntimeError{Msg: "step() expects a range as first argument."})
+	}
+	step, ok := args[1].(float64)
+	if !ok || step == 0 || math.IsInf(step, 0) || math.IsNaN(step) {
+		panic(&RuntimeError{Msg: "step() expects a finite non-zero number as second argument."})
+	}
+	if msg := checkRange(r.Start, r.End, step); msg != "" {
+		panic(&RuntimeError{Msg: msg})
+	}
+	return &Range{Start: r.Start, End: r.End, Step: step, Inclusive: r.Inclusive}
+}
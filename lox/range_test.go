@@ -0,0 +1,125 @@
+package lox
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestScanRange ...
+func TestScanRange(t *testing.T) {
+	cases := map[string][]TokenType{
+		"1..5":  {TokenTypeNumber, TokenTypeDotDot, TokenTypeNumber, TokenTypeEOF},
+		"1..=5": {TokenTypeNumber, TokenTypeDotDotEqual, TokenTypeNumber, TokenTypeEOF},
+		"1.5":   {TokenTypeNumber, TokenTypeEOF},
+		"a..b":  {TokenTypeIdentifier, TokenTypeDotDot, TokenTypeIdentifier, TokenTypeEOF},
+	}
+	for source, expected := range cases {
+		tokens := NewScanner(source).ScanTokens()
+		if len(tokens) != len(expected) {
+			t.Errorf("%q: got %d tokens, expected %d", source, len(tokens), len(expected))
+			continue
+		}
+		for idx, token := range tokens {
+			if token.Type != expected[idx] {
+				t.Errorf("%q: token %d is %s, expected %s", source, idx, token.Type, expected[idx])
+			}
+		}
+	}
+}
+
+// TestRange ...
+func TestRange(t *testing.T) {
+	cases := []struct {
+		r        *Range
+		len      int
+		in       []float64
+		notIn    []float64
+		expected string
+	}{
+		{NewRange(1, 5, false), 4, []float64{1, 4}, []float64{0, 5, 1.5}, "[1, 2, 3, 4]"},
+		{NewRange(1, 5, true), 5, []float64{1, 5}, []float64{6}, "[1, 2, 3, 4, 5]"},
+		{&Range{Start: 0, End: 10, Step: 3}, 4, []float64{0, 9}, []float64{10, 4}, "[0, 3, 6, 9]"},
+		{&Range{Start: 5, End: 1, Step: -2, Inclusive: true}, 3, []float64{5, 1}, []float64{2}, "[5, 3, 1]"},
+		{NewRange(5, 1, false), 0, nil, []float64{5}, "[]"},
+		{&Range{Start: 0, End: 1, Step: 0.1}, 10, []float64{0.3, 0.7, 0.9}, []float64{1, 0.35}, "[0, 0.1, 0.2, 0.30000000000000004, 0.4, 0.5, 0.6000000000000001, 0.7000000000000001, 0.8, 0.9]"},
+		{&Range{Start: 0, End: 0.3, Step: 0.1, Inclusive: true}, 4, []float64{0.3}, []float64{0.4}, "[0, 0.1, 0.2, 0.30000000000000004]"},
+	}
+	for _, c := range cases {
+		if got := c.r.Len(); got != c.len {
+			t.Errorf("%s: Len() = %d, expected %d", c.r, got, c.len)
+		}
+		for _, n := range c.in {
+			if !c.r.Contains(n) {
+				t.Errorf("%s: expected to contain %v", c.r, n)
+			}
+		}
+		for _, n := range c.notIn {
+			if c.r.Contains(n) {
+				t.Errorf("%s: expected not to contain %v", c.r, n)
+			}
+		}
+		got := ToList{}.Call(nil, []interface{}{c.r}).(*List).String()
+		if got != c.expected {
+			t.Errorf("%s: list() = %s, expected %s", c.r, got, c.expected)
+		}
+	}
+}
+
+// TestRangeBounds checks that ranges without a finite number of elements
+// are rejected when they are made.
+func TestRangeBounds(t *testing.T) {
+	tests := []struct {
+		source string
+		msg    string
+	}{
+		{"0..inf;", "Range bounds must be finite numbers."},
+		{"nan..=1;", "Range bounds must be finite numbers."},
+		{"0..big;", "Range has too many elements."},
+		{"step(0..1, tiny);", "Range has too many elements."},
+		{"step(0..1, 0);", "step() expects a finite non-zero number as second argument."},
+		{"step(0..1, nan);", "step() expects a finite non-zero number as second argument."},
+	}
+	for _, test := range tests {
+		i := NewInterpreter(WithStderr(&bytes.Buffer{}))
+		i.Define("inf", math.Inf(1))
+		i.Define("nan", math.NaN())
+		i.Define("big", 1e300)
+		i.Define("tiny", 1e-300)
+		_, err := i.Eval(test.source)
+		if err == nil || !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("%s: got %v, expected %q", test.source, err, test.msg)
+		}
+	}
+}
+
+// TestHugeRangeList checks that converting a range too large for memory to
+// a list raises a runtime error at the call instead of crashing.
+func TestHugeRangeList(t *testing.T) {
+	_, err := NewInterpreter(WithStderr(&bytes.Buffer{})).Eval("\nlist(0..=1000000000000000);")
+	if err == nil || err.Error() != "[line 2] Error: Range is too large to convert to a list.\n" {
+		t.Errorf("got %v, expected a runtime error on line 2", err)
+	}
+}
+
+// TestListContains checks that lists test membership with the equality of
+// '==', which does not panic on host values that Go cannot compare.
+func TestListContains(t *testing.T) {
+	l := NewList([]interface{}{[]int{1}, map[string]int{}, 1.0, nil})
+	tests := []struct {
+		value    interface{}
+		expected bool
+	}{
+		{1.0, true},
+		{nil, true},
+		{"1", false},
+		{[]int{1}, false},
+		{false, false},
+	}
+	for _, test := range tests {
+		if got := l.Contains(test.value); got != test.expected {
+			t.Errorf("Contains(%v) = %v, expected %v", test.value, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package lox
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Renderer writes diagnostics for people: the message, the position, the
+// source line with the span underlined, and the notes. In plain mode it
+// writes the one-line "[line N] Error at 'x': message" format instead,
+// which suits logs.
+type Renderer struct {
+	// Source is the text the spans of the diagnostics point into, and File
+	// its name, shown for spans that do not record one. Without Source, no
+	// source lines are shown.
+	Source string
+	File   string
+	Color  bool
+	Plain  bool
+}
+
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[1;31m"
+	colorBlue  = "\x1b[1;34m"
+	colorCyan  = "\x1b[1;36m"
+	colorYell  = "\x1b[1;33m"
+)
+
+var severityColors = [...]string{colorRed, colorYell, colorCyan}
+
+// Render writes d to w.
+func (r *Renderer) Render(w io.Writer, d Diagnostic) {
+	if r.Plain {
+		io.WriteString(w, d.Error())
+		for _, note := range d.Notes {
+			if note.Span.Line == 0 {
+				fmt.Fprintf(w, "Note: %s\n", note.Message)
+			} else {
+				fmt.Fprintf(w, "[line %d] Note: %s\n", note.Span.Line, note.Message)
+			}
+		}
+		return
+	}
+
+	buf := bytes.Buffer{}
+	r.header(&buf, d.Severity, d.Message)
+	gutter := r.gutterWidth(d)
+	r.snippet(&buf, d.Span, d.Severity, gutter)
+	for _, note := range d.Notes {
+		if note.Span.Line == 0 {
+			fmt.Fprintf(&buf, "%s %s= %snote%s: %s\n", strings.Repeat(" ", gutter), r.color(colorBlue), r.color(colorBold), r.color(colorReset), note.Message)
+			continue
+		}
+		r.header(&buf, SeverityNote, note.Message)
+		r.snippet(&buf, note.Span, SeverityNote, gutter)
+	}
+	for _, line := range tracebackLines(d.Trace) {
+		fmt.Fprintf(&buf, "%s %s=%s %s\n", strings.Repeat(" ", gutter), r.color(colorBlue), r.color(colorReset), line)
+	}
+	buf.WriteRune('\n')
+	w.Write(buf.Bytes())
+}
+
+// RenderAll writes every diagnostic in ds to w.
+func (r *Renderer) RenderAll(w io.Writer, ds Diagnostics) {
+	for _, d := range ds {
+		r.Render(w, d)
+	}
+}
+
+func (r *Renderer) color(code string) string {
+	if !r.Color {
+		return ""
+	}
+	return code
+}
+
+func (r *Renderer) header(buf *bytes.Buffer, severity Severity, message string) {
+	fmt.Fprintf(buf, "%s%s%s: %s%s%s\n", r.color(severityColors[severity]), severity, r.color(colorReset), r.color(colorBold), message, r.color(colorReset))
+}
+
+// gutterWidth is the width of the widest line number shown for d.
+func (r *Renderer) gutterWidth(d Diagnostic) int {
+	width := len(strconv.Itoa(d.Span.Line))
+	for _, note := range d.Notes {
+		if n := len(strconv.Itoa(note.Span.Line)); n > width {
+			width = n
+		}
+	}
+	return width
+}
+
+// snippet writes the position of span and, when the source has its line,
+// the line with the span underlined.
+func (r *Renderer) snippet(buf *bytes.Buffer, span Span, severity Severity, gutter int) {
+	if span.Line == 0 {
+		return
+	}
+	pad := strings.Repeat(" ", gutter)
+	blue, reset := r.color(colorBlue), r.color(colorReset)
+	file := span.File
+	if file == "" {
+		file = r.File
+	}
+	if file == "" {
+		file = "<input>"
+	}
+	position := fmt.Sprintf("%s:%d", file, span.Line)
+	if span.Column > 0 {
+		position += fmt.Sprintf(":%d", span.Column)
+	}
+	fmt.Fprintf(buf, "%s%s-->%s %s\n", pad, blue, reset, position)
+
+	lines := strings.Split(r.Source, "\n")
+	if r.Source == "" || span.Line > len(lines) {
+		return
+	}
+	line := strings.TrimRight(lines[span.Line-1], "\r")
+	fmt.Fprintf(buf, "%s %s|%s\n", pad, blue, reset)
+	fmt.Fprintf(buf, "%s%*d |%s %s\n", blue, gutter, span.Line, reset, line)
+	if span.Column == 0 || span.Column > len(line)+1 {
+		return
+	}
+
+	// Columns count bytes; pad with a space per character, keeping tabs in
+	// the indentation so the carets line up.
+	indent := make([]rune, 0, span.Column-1)
+	for _, c := range line[:span.Column-1] {
+		if c != '\t' {
+			c = ' '
+		}
+		indent = append(indent, c)
+	}
+	end := span.Column - 1 + span.End - span.Start
+	if end > len(line) {
+		end = len(line)
+	}
+	length := utf8.RuneCountInString(line[span.Column-1 : end])
+	if length < 1 {
+		length = 1
+	}
+	mark := "^"
+	if severity == SeverityNote {
+		mark = "-"
+	}
+	fmt.Fprintf(buf, "%s %s|%s %s%s%s\n", pad, blue, reset, string(indent), r.color(severityColors[severity])+strings.Repeat(mark, length), reset)
+}
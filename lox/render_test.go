@@ -0,0 +1,101 @@
+package lox
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestRender ...
+func TestRender(t *testing.T) {
+	source := "var a = 1;\nif (a) {\n\tprint (a + ;\n"
+	_, err := ParseFile("main.lox", source)
+	ds := err.(ErrorList).Diagnostics()
+
+	buf := bytes.Buffer{}
+	(&Renderer{Source: source}).RenderAll(&buf, ds)
+	want := `error: Expect expression.
+ --> main.lox:3:13
+  |
+3 | 	print (a + ;
+  | 	           ^
+
+error: Expect '}' after block.
+ --> main.lox:4:1
+  |
+4 | 
+  | ^
+note: to match this '{'
+ --> main.lox:2:8
+  |
+2 | if (a) {
+  |        -
+
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nexpected\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	(&Renderer{Source: source, Plain: true}).RenderAll(&buf, ds)
+	want = "[line 3] Error at ';': Expect expression.\n" +
+		"[line 4] Error at end: Expect '}' after block.\n" +
+		"[line 2] Note: to match this '{'\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nexpected\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	(&Renderer{Source: "print 1;\nprint 2 - nil;", File: "run.lox"}).Render(&buf, Diagnose(&RuntimeError{Line: 2, Msg: "Operands must be numbers."}))
+	want = "error: Operands must be numbers.\n --> run.lox:2\n  |\n2 | print 2 - nil;\n\n"
+	if buf.String() != want {
+		t.Errorf("got\n%q\nexpected\n%q", buf.String(), want)
+	}
+
+	// Carets line up under non-ASCII text.
+	source = `print "é" + 1 - 2;`
+	stmts, err := Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds = NewInterpreter(WithStderr(&bytes.Buffer{})).Interpret(stmts)
+	buf.Reset()
+	(&Renderer{Source: source}).RenderAll(&buf, ds)
+	want = `error: Operands must be numbers.
+ --> <input>:1:16
+  |
+1 | print "é" + 1 - 2;
+  |               ^
+
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nexpected\n%s", buf.String(), want)
+	}
+}
+
+// TestRenderEngines checks that every engine locates its runtime errors
+// well enough for the renderer to draw a caret under the culprit.
+func TestRenderEngines(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"var n = 1;\nprint -\"a\";", "2 | print -\"a\";\n  |       ^\n"},
+		{"var n = 1;\nn(2);", "2 | n(2);\n  |    ^\n"},
+		{"var n = 1;\nprint missing + n;", "2 | print missing + n;\n  |       ^^^^^^^\n"},
+		{"var n = 1;\nlist(n);", "2 | list(n);\n  |       ^\n"},
+	}
+	for _, test := range tests {
+		stmts, err := Parse(test.source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, run := range engines(stmts) {
+			ds := run(NewInterpreter(WithStderr(&bytes.Buffer{})))
+			buf := bytes.Buffer{}
+			(&Renderer{Source: test.source}).RenderAll(&buf, ds)
+			if !bytes.Contains(buf.Bytes(), []byte(test.want)) {
+				t.Errorf("%s: %q rendered\n%s\nexpected it to contain\n%s", name, test.source, buf.String(), test.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package lox
+
+// Resolver assigns every local variable a slot in the environment of the
+// scope declaring it, and records on each variable expression how many
+// environments up that scope is. The Interpreter then reads locals by
+// index; only globals are looked up by name.
+//
+// Its scopes mirror the environments the Interpreter creates: one per
+// block, per function call (parameters and body share it) and per select
+// clause.
+//
+// In DialectStrict it also reports the errors the book's resolver does
+// about locals: one declared twice in a scope, or read in its own
+// initializer. The parser rejects a return outside of a function.
+type Resolver struct {
+	scopes  []*resolverScope
+	dialect Dialect
+	errors  []error
+}
+
+type resolverScope struct {
+	slots map[string]int
+	// declared is the name token of each variable, for the notes of the
+	// strict errors.
+	declared map[string]Token
+	// size points at the field of the statement that records how many
+	// slots the scope needs.
+	size *int
+	// initializing is the name of the variable whose initializer is being
+	// resolved, with an empty lexeme outside initializers.
+	initializing Token
+}
+
+// NewResolver ...
+func NewResolver() *Resolver {
+	return &Resolver{}
+}
+
+// SetDialect sets the dialect of the statements. In DialectStrict the
+// resolver reports the static errors of the book.
+func (r *Resolver) SetDialect(d Dialect) {
+	r.dialect = d
+}
+
+// Errors returns the errors found by Resolve.
+func (r *Resolver) Errors() []error {
+	return r.errors
+}
+
+// strictErr reports an error at t in DialectStrict, with a note pointing
+// at the declaration of the variable it is about.
+func (r *Resolver) strictErr(t Token, message string, declaration Token) {
+	if r.dialect == DialectStrict {
+		notes := []Note{{Message: "variable declared here", Span: declaration.Span()}}
+		r.errors = append(r.errors, &ParseError{Token: t, Msg: message, Notes: notes})
+	}
+}
+
+// Resolve ...
+func (r *Resolver) Resolve(statements []Stmt) {
+	for _, statement := range statements {
+		r.statement(statement)
+	}
+}
+
+func (r *Resolver) statement(stmt Stmt) {
+	if stmt != nil {
+		stmt.Accept(r)
+	}
+}
+
+func (r *Resolver) expression(expr Expr) {
+	if expr != nil {
+		expr.Accept(r)
+	}
+}
+
+func (r *Resolver) scope(size *int, names []Token, statements []Stmt) {
+	r.scopes = append(r.scopes, &resolverScope{slots: make(map[string]int), declared: make(map[string]Token), size: size})
+	*size = 0
+	for _, name := range names {
+		r.declare(name)
+	}
+	r.Resolve(statements)
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// declare returns the slot of a new variable in the innermost scope, or -1
+// at the top level. Declaring a name twice in a scope reuses its slot, as
+// redefining a global replaces it.
+func (r *Resolver) declare(name Token) int {
+	if len(r.scopes) == 0 {
+		return -1
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	if slot, ok := scope.slots[name.Lexeme]; ok {
+		r.strictErr(name, "Already a variable with this name in this scope.", scope.declared[name.Lexeme])
+		return slot
+	}
+	slot := *scope.size
+	scope.slots[name.Lexeme] = slot
+	scope.declared[name.Lexeme] = name
+	*scope.size++
+	return slot
+}
+
+func (r *Resolver) resolveLocal(name string) *Binding {
+	for idx := len(r.scopes) - 1; idx >= 0; idx-- {
+		if slot, ok := r.scopes[idx].slots[name]; ok {
+			return &Binding{Depth: len(r.scopes) - 1 - idx, Slot: slot}
+		}
+	}
+	return nil
+}
+
+// VisitExpressionStmt ...
+func (r *Resolver) VisitExpressionStmt(stmt *ExpressionStmt) interface{} {
+	r.expression(stmt.Expression)
+	return nil
+}
+
+// VisitPrintStmt ...
+func (r *Resolver) VisitPrintStmt(stmt *PrintStmt) interface{} {
+	r.expression(stmt.Expression)
+	return nil
+}
+
+// VisitVarStmt resolves the initializer before declaring the variable, so
+// the initializer sees any outer variable of the same name.
+func (r *Resolver) VisitVarStmt(stmt *VarStmt) interface{} {
+	if len(r.scopes) > 0 {
+		scope := r.scopes[len(r.scopes)-1]
+		scope.initializing = stmt.Name
+		r.expression(stmt.Initializer)
+		scope.initializing = Token{}
+	} else {
+		r.expression(stmt.Initializer)
+	}
+	stmt.Slot = r.declare(stmt.Name)
+	return nil
+}
+
+// VisitBlockStmt ...
+func (r *Resolver) VisitBlockStmt(stmt *BlockStmt) interface{} {
+	r.scope(&stmt.Slots, nil, stmt.Statements)
+	return nil
+}
+
+// VisitIfStmt ...
+func (r *Resolver) VisitIfStmt(stmt *IfStmt) interface{} {
+	r.expression(stmt.Condition)
+	r.statement(stmt.ThenBranch)
+	r.statement(stmt.ElseBranch)
+	return nil
+}
+
+// VisitWhileStmt ...
+func (r *Resolver) VisitWhileStmt(stmt *WhileStmt) interface{} {
+	r.expression(stmt.Condition)
+	r.statement(stmt.Body)
+	return nil
+}
+
+// VisitFunctionStmt declares the function before resolving its body so
+// that it can refer to itself.
+func (r *Resolver) VisitFunctionStmt(stmt *FunctionStmt) interface{} {
+	stmt.Slot = r.declare(stmt.Name)
+	r.scope(&stmt.Slots, stmt.Params, stmt.Body)
+	return nil
+}
+
+// VisitReturnStmt ...
+func (r *Resolver) VisitReturnStmt(stmt *ReturnStmt) interface{} {
+	r.expression(stmt.Value)
+	return nil
+}
+
+// VisitYieldStmt ...
+func (r *Resolver) VisitYieldStmt(stmt *YieldStmt) interface{} {
+	r.expression(stmt.Value)
+	return nil
+}
+
+// VisitSpawnStmt ...
+func (r *Resolver) VisitSpawnStmt(stmt *SpawnStmt) interface{} {
+	r.expression(stmt.Call)
+	return nil
+}
+
+// VisitSelectStmt ...
+func (r *Resolver) VisitSelectStmt(stmt *SelectStmt) interface{} {
+	for _, c := range stmt.Cases {
+		r.expression(c.Channel)
+		r.expression(c.Value)
+	}
+	for _, c := range stmt.Cases {
+		var names []Token
+		if c.Name != nil {
+			names = append(names, *c.Name)
+		}
+		r.scope(&c.Slots, names, c.Body)
+	}
+	if stmt.Default != nil {
+		r.scope(&stmt.DefaultSlots, nil, stmt.Default)
+	}
+	return nil
+}
+
+// VisitLiteralExpr ...
+func (r *Resolver) VisitLiteralExpr(expr *ExprLiteral) interface{} {
+	return nil
+}
+
+// VisitGroupingExpr ...
+func (r *Resolver) VisitGroupingExpr(expr *ExprGrouping) interface{} {
+	r.expression(expr.Expr)
+	return nil
+}
+
+// VisitVarExpr ...
+func (r *Resolver) VisitVarExpr(expr *ExprVar) interface{} {
+	if len(r.scopes) > 0 {
+		if initializing := r.scopes[len(r.scopes)-1].initializing; initializing.Lexeme == expr.Name.Lexeme {
+			r.strictErr(expr.Name, "Can't read local variable in its own initializer.", initializing)
+		}
+	}
+	expr.Binding = r.resolveLocal(expr.Name.Lexeme)
+	return nil
+}
+
+// VisitAssignExpr ...
+func (r *Resolver) VisitAssignExpr(expr *ExprAssign) interface{} {
+	r.expression(expr.Value)
+	expr.Binding = r.resolveLocal(expr.Name.Lexeme)
+	return nil
+}
+
+// VisitUnaryExpr ...
+func (r *Resolver) VisitUnaryExpr(expr *ExprUnary) interface{} {
+	r.expression(expr.Right)
+	return nil
+}
+
+// VisitBinaryExpr ...
+func (r *Resolver) VisitBinaryExpr(expr *ExprBinary) interface{} {
+	r.expression(expr.Left)
+	r.expression(expr.Right)
+	return nil
+}
+
+// VisitLogicalExpr ...
+func (r *Resolver) VisitLogicalExpr(expr *ExprLogical) interface{} {
+	r.expression(expr.Left)
+	r.expression(expr.Right)
+	return nil
+}
+
+// VisitCallExpr ...
+func (r *Resolver) VisitCallExpr(expr *ExprCall) interface{} {
+	r.expression(expr.Callee)
+	for _, arg := range expr.Arguments {
+		r.expression(*arg)
+	}
+	return nil
+}
+
+// VisitGetExpr ...
+func (r *Resolver) VisitGetExpr(expr *ExprGet) interface{} {
+	r.expression(expr.Object)
+	return nil
+}
+
+// VisitSetExpr ...
+func (r *Resolver) VisitSetExpr(expr *ExprSet) interface{} {
+	r.expression(expr.Object)
+	r.expression(expr.Value)
+	return nil
+}
+
+// VisitAwaitExpr ...
+func (r *Resolver) VisitAwaitExpr(expr *ExprAwait) interface{} {
+	r.expression(expr.Value)
+	return nil
+}
@@ -1,72 +1,120 @@
 package lox
 
 import (
-	"fmt"
 	"strconv"
 	"unicode"
 )
 
-var start int
-var currentScannerPointer int
-var line int
-var tokens []*Token
-var keywords map[string]TokenType
+// keywords is read-only, so scanners can share it.
+var keywords = map[string]TokenType{
+	"and":     TokenTypeAnd,
+	"async":   TokenTypeAsync,
+	"await":   TokenTypeAwait,
+	"case":    TokenTypeCase,
+	"class":   TokenTypeClass,
+	"default": TokenTypeDefault,
+	"else":    TokenTypeElse,
+	"false":   TokenTypeFalse,
+	"for":     TokenTypeFor,
+	"fun":     TokenTypeFun,
+	"if":      TokenTypeIf,
+	"nil":     TokenTypeNil,
+	"or":      TokenTypeOr,
+	"print":   TokenTypePrint,
+	"return":  TokenTypeReturn,
+	"select":  TokenTypeSelect,
+	"spawn":   TokenTypeSpawn,
+	"super":   TokenTypeSuper,
+	"this":    TokenTypeThis,
+	"true":    TokenTypeTrue,
+	"var":     TokenTypeVar,
+	"while":   TokenTypeWhile,
+	"yield":   TokenTypeYield,
+}
 
 // Scanner ...
 type Scanner struct {
-	source string
+	file    string
+	source  string
+	tokens  []*Token
+	start   int
+	current int
+	line    int
+	errors  []error
+	// lineStart is the offset of the current line; startLine and
+	// startColumn are where the token being scanned begins.
+	lineStart   int
+	startLine   int
+	startColumn int
+	dialect     Dialect
 }
 
 // NewScanner ...
 func NewScanner(source string) *Scanner {
+	return NewFileScanner("", source)
+}
+
+// NewFileScanner returns a scanner whose tokens record that they come from
+// the named file.
+func NewFileScanner(file string, source string) *Scanner {
 	s := new(Scanner)
+	s.file = file
 	s.source = source
-
-	tokens = make([]*Token, 0)
-	start = 0
-	currentScannerPointer = 0
-	line = 1
-
-	keywords = map[string]TokenType{
-		"and":    TokenTypeAnd,
-		"class":  TokenTypeClass,
-		"else":   TokenTypeElse,
-		"false":  TokenTypeFalse,
-		"for":    TokenTypeFor,
-		"fun":    TokenTypeFun,
-		"if":     TokenTypeIf,
-		"nil":    TokenTypeNil,
-		"or":     TokenTypeOr,
-		"print":  TokenTypePrint,
-		"return": TokenTypeReturn,
-		"super":  TokenTypeSuper,
-		"this":   TokenTypeThis,
-		"true":   TokenTypeTrue,
-		"var":    TokenTypeVar,
-		"while":  TokenTypeWhile,
-	}
+	s.tokens = make([]*Token, 0)
+	s.start = 0
+	s.current = 0
+	s.line = 1
 	return s
 }
 
+// SetDialect sets the dialect of the source. In DialectStrict the keywords
+// of the extensions scan as identifiers.
+func (s *Scanner) SetDialect(d Dialect) {
+	s.dialect = d
+}
+
 // ScanTokens ...
-func (s Scanner) ScanTokens() []*Token {
+func (s *Scanner) ScanTokens() []*Token {
 	for {
 		if s.isAtEnd() {
 			break
 		}
-		start = currentScannerPointer
+		s.start = s.current
+		s.startLine = s.line
+		s.startColumn = s.current - s.lineStart + 1
 		s.scanToken()
 	}
 
-	tokens = append(tokens, NewToken(TokenTypeEOF, "", nil, line))
-	return tokens
+	s.start = s.current
+	s.startLine = s.line
+	s.startColumn = s.current - s.lineStart + 1
+	s.addToken(TokenTypeEOF)
+	return s.tokens
 }
 
-func (s Scanner) isAtEnd() bool {
-	return currentScannerPointer >= len(s.source)
+// Errors returns the lexical errors found by ScanTokens.
+func (s *Scanner) Errors() []error {
+	return s.errors
 }
 
-func (s Scanner) scanToken() {
+// Diagnostics returns the lexical errors found by ScanTokens as
+// diagnostics.
+func (s *Scanner) Diagnostics() Diagnostics {
+	return ErrorList(s.errors).Diagnostics()
+}
+
+func (s *Scanner) error(message string) error {
+	span := Span{File: s.file, Line: s.startLine, Column: s.startColumn, Start: s.start, End: s.current}
+	e := &LexError{Line: s.line, Msg: message, Span: span}
+	s.errors = append(s.errors, e)
+	return e
+}
+
+func (s *Scanner) isAtEnd() bool {
+	return s.current >= len(s.source)
+}
+
+func (s *Scanner) scanToken() {
 	switch c := s.advance(); c {
 	case '(':
 		s.addToken(TokenTypeLeftParen)
@@ -79,7 +127,15 @@ func (s Scanner) scanToken() {
 	case ',':
 		s.addToken(TokenTypeComma)
 	case '.':
-		s.addToken(TokenTypeDot)
+		if s.match('.') {
+			if s.match('=') {
+				s.addToken(TokenTypeDotDotEqual)
+			} else {
+				s.addToken(TokenTypeDotDot)
+			}
+		} else {
+			s.addToken(TokenTypeDot)
+		}
 	case '-':
 		s.addToken(TokenTypeMinus)
 	case '+':
@@ -126,7 +182,7 @@ func (s Scanner) scanToken() {
 	case ' ', '\r', '\t':
 		break
 	case '\n':
-		line++
+		s.newline()
 	case '"':
 		s.stringTokenizer()
 	default:
@@ -135,40 +191,40 @@ func (s Scanner) scanToken() {
 		} else if s.isAlpha(c) {
 			s.identifierTokenizer()
 		} else {
-			fmt.Println(&RuntimeError{line, "Unexpected character."})
+			s.error("Unexpected character.")
 			return
 		}
 	}
 }
 
-func (s Scanner) isAlpha(c byte) bool {
+func (s *Scanner) isAlpha(c byte) bool {
 	return unicode.IsLetter(rune(c)) || c == '_'
 	//	return !((c < 'a' || c > 'z') && (c < 'A' && c > 'Z') && c != '_')
 }
 
-func (s Scanner) isAlphaNumeric(c byte) bool {
+func (s *Scanner) isAlphaNumeric(c byte) bool {
 	return s.isAlpha(c) || s.isDigit(c)
 }
 
-func (s Scanner) identifierTokenizer() {
+func (s *Scanner) identifierTokenizer() {
 	for {
 		if !s.isAlphaNumeric(s.peek()) {
 			break
 		}
 		s.advance()
 	}
-	tokenType, ok := keywords[s.source[start:currentScannerPointer]]
-	if !ok {
+	tokenType, ok := keywords[s.source[s.start:s.current]]
+	if !ok || (s.dialect == DialectStrict && extensionKeywords[tokenType]) {
 		tokenType = TokenTypeIdentifier
 	}
 	s.addToken(tokenType)
 }
 
-func (s Scanner) isDigit(c byte) bool {
+func (s *Scanner) isDigit(c byte) bool {
 	return c >= '0' && c <= '9'
 }
 
-func (s Scanner) numberTokenizer() error {
+func (s *Scanner) numberTokenizer() error {
 	for {
 		if !s.isDigit(s.peek()) {
 			break
@@ -176,6 +232,8 @@ func (s Scanner) numberTokenizer() error {
 		s.advance()
 	}
 
+	// A fractional part needs a digit after the dot, so "1..5" scans as
+	// 1, .., 5 rather than 1. followed by .5
 	if s.peek() == '.' && s.isDigit(s.peekNext()) {
 		s.advance()
 		for {
@@ -186,72 +244,82 @@ func (s Scanner) numberTokenizer() error {
 		}
 	}
 
-	value, e := strconv.ParseFloat(s.source[start:currentScannerPointer], 64)
+	value, e := strconv.ParseFloat(s.source[s.start:s.current], 64)
 	if e != nil {
-		return &RuntimeError{line, e.Error()}
+		return s.error(e.Error())
 	}
 	s.addTokenWithLiteral(TokenTypeNumber, value)
 	return nil
 }
 
-func (s Scanner) peekNext() byte {
-	if currentScannerPointer+1 >= len(s.source) {
+func (s *Scanner) peekNext() byte {
+	if s.current+1 >= len(s.source) {
 		return 0
 	}
 
-	return s.source[currentScannerPointer+1]
+	return s.source[s.current+1]
 }
 
-func (s Scanner) stringTokenizer() error {
+func (s *Scanner) stringTokenizer() error {
 	for {
 		if s.isAtEnd() || s.peek() == '"' {
 			break
 		}
-		if s.peek() == '\n' {
-			line++
+		if s.advance() == '\n' {
+			s.newline()
 		}
-		s.advance()
 	}
 
 	if s.isAtEnd() {
-		return &RuntimeError{line, "Unterminated String"}
+		return s.error("Unterminated string.")
 	}
 
 	s.advance()
-	text := s.source[start+1 : currentScannerPointer-1]
+	text := s.source[s.start+1 : s.current-1]
 	s.addTokenWithLiteral(TokenTypeString, text)
 	return nil
 }
 
-func (s Scanner) peek() byte {
+func (s *Scanner) peek() byte {
 	if s.isAtEnd() {
 		return 0
 	}
-	return s.source[currentScannerPointer]
+	return s.source[s.current]
 }
 
-func (s Scanner) match(expected byte) bool {
+func (s *Scanner) match(expected byte) bool {
 	if s.isAtEnd() {
 		return false
 	}
 
-	if s.source[currentScannerPointer] != expected {
+	if s.source[s.current] != expected {
 		return false
 	}
-	currentScannerPointer++
+	s.current++
 	return true
 }
 
-func (s Scanner) advance() byte {
-	currentScannerPointer++
-	return s.source[currentScannerPointer-1]
+// newline is called after consuming a line break.
+func (s *Scanner) newline() {
+	s.line++
+	s.lineStart = s.current
+}
+
+func (s *Scanner) advance() byte {
+	s.current++
+	return s.source[s.current-1]
 }
 
-func (s Scanner) addToken(tokenType TokenType) {
+func (s *Scanner) addToken(tokenType TokenType) {
 	s.addTokenWithLiteral(tokenType, nil)
 }
 
-func (s Scanner) addTokenWithLiteral(tokenType TokenType, literal interface{}) {
-	text := s.source[start:currentScannerPointer]
-	tokens = append(tokens, NewToken(tokenType, text, literal, line))
+func (s *Scanner) addTokenWithLiteral(tokenType TokenType, literal interface{}) {
+	text := s.source[s.start:s.current]
+	t := NewToken(tokenType, text, literal, s.startLine)
+	t.Column = s.startColumn
+	t.Start = s.start
+	t.End = s.current
+	t.File = s.file
+	s.tokens = append(s.tokens, t)
 }
@@ -0,0 +1,230 @@
+package lox
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// scheduler tracks the tasks of an interpreter and owns the lock guarding
+// every channel created by it. Because all channel operations happen under
+// that one lock, a task is only marked as blocked when its operation truly
+// cannot proceed, which makes deadlock detection exact.
+type scheduler struct {
+	mu       sync.Mutex
+	nextID   int
+	channels int
+	main     *task
+	tasks    map[int]*task
+}
+
+// task is a single thread of Lox execution: the main program or a call
+// started with spawn.
+type task struct {
+	id      int
+	name    string
+	line    int
+	active  bool
+	blocked string
+	waiter  *waiter
+}
+
+// waiter is a blocked task parked on one or more channel queues.
+type waiter struct {
+	task    *task
+	done    bool
+	fired   int
+	value   interface{}
+	ok      bool
+	closed  bool
+	aborted *DeadlockError
+	wake    chan struct{}
+}
+
+// pending is a waiter's entry in the queue of one channel.
+type pending struct {
+	w     *waiter
+	idx   int
+	value interface{}
+}
+
+// chanOp is one send or receive offered to choose.
+type chanOp struct {
+	ch    *Channel
+	send  bool
+	value interface{}
+}
+
+// taskAborted unwinds the goroutine of a spawned task after a deadlock.
+type taskAborted struct{}
+
+// DeadlockError is raised in the main task when every task is blocked.
+type DeadlockError struct {
+	Tasks []string
+}
+
+// Error ...
+func (de *DeadlockError) Error() string {
+	buf := bytes.Buffer{}
+	buf.WriteString("fatal error: all tasks are asleep - deadlock!\n")
+	for _, t := range de.Tasks {
+		buf.WriteString("\n")
+		buf.WriteString(t)
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+func newScheduler() *scheduler {
+	s := new(scheduler)
+	s.tasks = make(map[int]*task)
+	s.main = &task{id: 0, name: "main"}
+	s.tasks[0] = s.main
+	s.nextID = 1
+	return s
+}
+
+// enter marks t as running Lox code. The main task is only active while
+// Interpret runs, so tasks parked between REPL lines are not a deadlock.
+func (s *scheduler) enter(t *task) {
+	s.mu.Lock()
+	t.active = true
+	s.mu.Unlock()
+}
+
+func (s *scheduler) leave(t *task) {
+	s.mu.Lock()
+	t.active = false
+	s.mu.Unlock()
+}
+
+func (s *scheduler) spawn(name string, line int) *task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := &task{id: s.nextID, name: name, line: line, active: true}
+	s.nextID++
+	s.tasks[t.id] = t
+	return t
+}
+
+func (s *scheduler) exit(t *task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tasks, t.id)
+	s.checkDeadlock()
+}
+
+// choose performs the first ready operation among ops, picked at random like
+// Go's select. If none is ready it returns -1 when block is false, and
+// otherwise parks t until another task completes one of them.
+func (s *scheduler) choose(t *task, ops []chanOp, block bool, blockedOn string) (int, interface{}, bool) {
+	s.mu.Lock()
+	for _, idx := range rand.Perm(len(ops)) {
+		op := ops[idx]
+		if op.send {
+			if op.ch.closed {
+				s.mu.Unlock()
+				panic(&RuntimeError{Msg: "Send on closed channel."})
+			}
+			if op.ch.trySend(op.value) {
+				s.mu.Unlock()
+				return idx, nil, true
+			}
+		} else if value, ok, ready := op.ch.tryRecv(); ready {
+			s.mu.Unlock()
+			return idx, value, ok
+		}
+	}
+	if !block {
+		s.mu.Unlock()
+		return -1, nil, false
+	}
+
+	w := &waiter{task: t, wake: make(chan struct{}, 1)}
+	for idx, op := range ops {
+		p := &pending{w: w, idx: idx, value: op.value}
+		if op.send {
+			op.ch.sendq = append(op.ch.sendq, p)
+		} else {
+			op.ch.recvq = append(op.ch.recvq, p)
+		}
+	}
+	t.waiter = w
+	t.blocked = blockedOn
+	s.checkDeadlock()
+	s.mu.Unlock()
+
+	<-w.wake
+
+	s.mu.Lock()
+	for _, op := range ops {
+		op.ch.dequeue(w)
+	}
+	s.mu.Unlock()
+
+	if w.aborted != nil {
+		if t == s.main {
+			panic(w.aborted)
+		}
+		panic(taskAborted{})
+	}
+	if w.closed {
+		panic(&RuntimeError{Msg: "Send on closed channel."})
+	}
+	return w.fired, w.value, w.ok
+}
+
+// complete hands value to a parked waiter and makes its task runnable. The
+// caller must hold s.mu.
+func (s *scheduler) complete(p *pending, value interface{}, ok bool) {
+	w := p.w
+	w.done = true
+	w.fired = p.idx
+	w.value = value
+	w.ok = ok
+	w.task.waiter = nil
+	w.task.blocked = ""
+	w.wake <- struct{}{}
+}
+
+// checkDeadlock aborts every task once all active tasks are blocked while
+// the main task is running. The caller must hold s.mu.
+func (s *scheduler) checkDeadlock() {
+	if !s.main.active {
+		return
+	}
+	blocked := make([]*task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		if t.active && t.waiter == nil {
+			return
+		}
+		if t.waiter != nil {
+			blocked = append(blocked, t)
+		}
+	}
+	sort.Slice(blocked, func(a, b int) bool {
+		return blocked[a].id < blocked[b].id
+	})
+	de := &DeadlockError{Tasks: make([]string, 0, len(blocked))}
+	for _, t := range blocked {
+		de.Tasks = append(de.Tasks, t.String())
+	}
+	for _, t := range blocked {
+		w := t.waiter
+		w.done = true
+		w.aborted = de
+		t.waiter = nil
+		t.blocked = ""
+		w.wake <- struct{}{}
+	}
+}
+
+// String ...
+func (t *task) String() string {
+	if t.id == 0 {
+		return fmt.Sprintf("task 0 [main]: blocked on %s", t.blocked)
+	}
+	return fmt.Sprintf("task %d [%s, spawned at line %d]: blocked on %s", t.id, t.name, t.line, t.blocked)
+}
@@ -0,0 +1,43 @@
+package lox
+
+// Span is a range of the source: the bytes from Start up to End, beginning
+// at Line and Column. Columns count bytes from 1. The zero Span is an
+// unknown position.
+type Span struct {
+	File   string
+	Line   int
+	Column int
+	Start  int
+	End    int
+}
+
+// To returns the span from the start of s to the end of end.
+func (s Span) To(end Span) Span {
+	if s.Line == 0 {
+		return end
+	}
+	if end.Line != 0 && end.End > s.End {
+		s.End = end.End
+	}
+	return s
+}
+
+// Node records where in the source an expression or statement was parsed
+// from. It is embedded in every Expr and Stmt.
+type Node struct {
+	Source Span
+}
+
+// Span ...
+func (n *Node) Span() Span {
+	return n.Source
+}
+
+func (n *Node) setSpan(span Span) {
+	n.Source = span
+}
+
+// spanSetter is implemented by the nodes the parser records spans on.
+type spanSetter interface {
+	setSpan(Span)
+}
@@ -0,0 +1,92 @@
+package lox
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+)
+
+// WithStdout makes print statements write to w instead of os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.stdout = newSyncWriter(w)
+	}
+}
+
+// WithStderr makes the interpreter write diagnostics, such as runtime
+// errors, to w instead of os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.stderr = newSyncWriter(w)
+	}
+}
+
+// WithRenderer makes the interpreter write the errors it reports with r
+// rather than in the one-line format of their Error methods.
+func WithRenderer(r *Renderer) Option {
+	return func(i *Interpreter) {
+		i.renderer = r
+	}
+}
+
+// WithStdin makes readLine() read from r instead of os.Stdin.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) {
+		i.stdin = &lineReader{r: bufio.NewReader(r)}
+	}
+}
+
+// syncWriter serializes the writes of concurrently running tasks, so that
+// writers such as bytes.Buffer can be used.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newSyncWriter(w io.Writer) *syncWriter {
+	if sw, ok := w.(*syncWriter); ok {
+		return sw
+	}
+	return &syncWriter{w: w}
+}
+
+func (sw *syncWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Write(p)
+}
+
+// lineReader serializes the reads of concurrently running tasks.
+type lineReader struct {
+	mu sync.Mutex
+	r  *bufio.Reader
+}
+
+func (lr *lineReader) readLine() (string, bool) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	line, err := lr.r.ReadString('\n')
+	if err != nil && line == "" {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), true
+}
+
+// ReadLine ...
+type ReadLine struct{}
+
+// Arity ...
+func (rl ReadLine) Arity() int {
+	return 0
+}
+
+// Call reads a line from the standard input of the interpreter, without
+// the line ending. It returns nil at the end of the input.
+func (rl ReadLine) Call(i *Interpreter, args []interface{}) interface{} {
+	line, ok := i.stdin.readLine()
+	if !ok {
+		return nil
+	}
+	return line
+}
@@ -0,0 +1,35 @@
+package lox
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestStdio checks that every engine reads from the configured stdin and
+// writes output and errors to the configured stdout and stderr.
+func TestStdio(t *testing.T) {
+	source := `
+var line = readLine();
+while (line) {
+  print line + "!";
+  line = readLine();
+}
+missing;
+clock(1);
+`
+	stmts, err := Parse(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, run := range engines(stmts) {
+		stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+		run(NewInterpreter(WithStdout(&stdout), WithStderr(&stderr), WithStdin(strings.NewReader("a\r\nb"))))
+		if got, want := stdout.String(), "a!\nb!\n"; got != want {
+			t.Errorf("%s: stdout = %q, want %q", name, got, want)
+		}
+		if got, want := stderr.String(), "[line 7] Error: Undefined variable 'missing'.\n"; got != want {
+			t.Errorf("%s: stderr = %q, want %q", name, got, want)
+		}
+	}
+}
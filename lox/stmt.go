@@ -3,10 +3,12 @@ package lox
 // Stmt ...
 type Stmt interface {
 	Accept(v StmtVisitor) interface{}
+	Span() Span
 }
 
 // ExpressionStmt ...
 type ExpressionStmt struct {
+	Node
 	Expression Expr
 }
 
@@ -22,6 +24,7 @@ func (stmt *ExpressionStmt) Accept(v StmtVisitor) interface{} {
 
 // PrintStmt ...
 type PrintStmt struct {
+	Node
 	Expression Expr
 }
 
@@ -37,13 +40,17 @@ func (stmt *PrintStmt) Accept(v StmtVisitor) interface{} {
 
 // VarStmt ...
 type VarStmt struct {
+	Node
 	Name        Token
 	Initializer Expr
+	// Slot is the index of a local variable in its environment, or -1 for
+	// a global.
+	Slot int
 }
 
 // NewVarStmt ...
 func NewVarStmt(name Token, initializer Expr) Stmt {
-	return &VarStmt{Name: name, Initializer: initializer}
+	return &VarStmt{Name: name, Initializer: initializer, Slot: -1}
 }
 
 // Accept ...
@@ -53,6 +60,7 @@ func (stmt *VarStmt) Accept(v StmtVisitor) interface{} {
 
 // IfStmt ...
 type IfStmt struct {
+	Node
 	Condition  Expr
 	ThenBranch Stmt
 	ElseBranch Stmt
@@ -70,7 +78,10 @@ func (stmt *IfStmt) Accept(v StmtVisitor) interface{} {
 
 // BlockStmt ...
 type BlockStmt struct {
+	Node
 	Statements []Stmt
+	// Slots is the number of variables declared directly in the block.
+	Slots int
 }
 
 // NewBlockStmt ...
@@ -85,6 +96,7 @@ func (stmt *BlockStmt) Accept(v StmtVisitor) interface{} {
 
 // WhileStmt ...
 type WhileStmt struct {
+	Node
 	Condition Expr
 	Body      Stmt
 }
@@ -101,14 +113,22 @@ func (stmt *WhileStmt) Accept(v StmtVisitor) interface{} {
 
 // FunctionStmt ...
 type FunctionStmt struct {
-	Name   Token
-	Params []Token
-	Body   []Stmt
+	Node
+	Name        Token
+	Params      []Token
+	Body        []Stmt
+	IsGenerator bool
+	IsAsync     bool
+	// Slot is the index of the function's variable, as for VarStmt. Slots
+	// is the size of the environment of a call, which holds the parameters
+	// followed by the variables declared directly in the body.
+	Slot  int
+	Slots int
 }
 
 // NewFunctionStmt ...
 func NewFunctionStmt(name Token, params []Token, body []Stmt) Stmt {
-	return &FunctionStmt{Name: name, Params: params, Body: body}
+	return &FunctionStmt{Name: name, Params: params, Body: body, IsGenerator: findYield(body) != nil, Slot: -1}
 }
 
 // Accept ...
@@ -118,6 +138,7 @@ func (stmt *FunctionStmt) Accept(v StmtVisitor) interface{} {
 
 // ReturnStmt ...
 type ReturnStmt struct {
+	Node
 	Keyword Token
 	Value   Expr
 }
@@ -131,3 +152,71 @@ func NewReturnStmt(keyword Token, value Expr) Stmt {
 func (stmt *ReturnStmt) Accept(v StmtVisitor) interface{} {
 	return v.VisitReturnStmt(stmt)
 }
+
+// YieldStmt ...
+type YieldStmt struct {
+	Node
+	Keyword Token
+	Value   Expr
+}
+
+// NewYieldStmt ...
+func NewYieldStmt(keyword Token, value Expr) Stmt {
+	return &YieldStmt{Keyword: keyword, Value: value}
+}
+
+// Accept ...
+func (stmt *YieldStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitYieldStmt(stmt)
+}
+
+// SpawnStmt ...
+type SpawnStmt struct {
+	Node
+	Keyword Token
+	Call    *ExprCall
+}
+
+// NewSpawnStmt ...
+func NewSpawnStmt(keyword Token, call *ExprCall) Stmt {
+	return &SpawnStmt{Keyword: keyword, Call: call}
+}
+
+// Accept ...
+func (stmt *SpawnStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitSpawnStmt(stmt)
+}
+
+// SelectCase is one "case" clause of a select statement. Operation is the
+// "send" or "recv" identifier; Name is the variable bound to the received
+// value, if any.
+type SelectCase struct {
+	Operation Token
+	Name      *Token
+	Channel   Expr
+	Value     Expr
+	Body      []Stmt
+	// Slots is the size of the environment of the body, whose first slot
+	// holds Name.
+	Slots int
+}
+
+// SelectStmt ...
+type SelectStmt struct {
+	Node
+	Keyword Token
+	Cases   []*SelectCase
+	// Default is nil when the select has no default clause.
+	Default      []Stmt
+	DefaultSlots int
+}
+
+// NewSelectStmt ...
+func NewSelectStmt(keyword Token, cases []*SelectCase, defaultBody []Stmt) Stmt {
+	return &SelectStmt{Keyword: keyword, Cases: cases, Default: defaultBody}
+}
+
+// Accept ...
+func (stmt *SelectStmt) Accept(v StmtVisitor) interface{} {
+	return v.VisitSelectStmt(stmt)
+}
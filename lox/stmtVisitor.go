@@ -10,4 +10,7 @@ type StmtVisitor interface {
 	VisitWhileStmt(stmt *WhileStmt) interface{}
 	VisitFunctionStmt(stmt *FunctionStmt) interface{}
 	VisitReturnStmt(stmt *ReturnStmt) interface{}
+	VisitYieldStmt(stmt *YieldStmt) interface{}
+	VisitSpawnStmt(stmt *SpawnStmt) interface{}
+	VisitSelectStmt(stmt *SelectStmt) interface{}
 }
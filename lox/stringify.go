@@ -0,0 +1,86 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Stringify returns the text print shows for value: nil, true and false,
+// numbers without a fractional part as integers, strings as they are,
+// functions as <fn name> and natives as <native fn>. Host values format
+// themselves with their String method.
+func Stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return formatNumber(v)
+	case string:
+		return v
+	case *Function:
+		return fmt.Sprintf("<fn %s>", v.Declaration.Name.Lexeme)
+	case Stringer:
+		return v.String()
+	case Callable:
+		return "<native fn>"
+	}
+	return fmt.Sprint(value)
+}
+
+// formatNumber writes whole numbers without a fraction or exponent, up to
+// where doubles stop being exact, and other numbers in the shortest form
+// that reads back as the same number.
+func formatNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "nan"
+	case math.IsInf(n, 1):
+		return "inf"
+	case math.IsInf(n, -1):
+		return "-inf"
+	case n == math.Trunc(n) && math.Abs(n) < 1<<53:
+		return strconv.FormatFloat(n, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
+
+// stringify is Stringify with the toString protocol: an object whose
+// toString property is a function without parameters is shown as what it
+// returns. The conversion at span calls toString like a call expression
+// there, so that it counts towards the recursion limit.
+func (i *Interpreter) stringify(value interface{}, at Span) string {
+	if i == nil {
+		return Stringify(value)
+	}
+	switch value.(type) {
+	case nil, bool, float64, string:
+		return Stringify(value)
+	}
+	o, ok := asObject(value)
+	if !ok {
+		return Stringify(value)
+	}
+	method, err := o.GetProperty("toString")
+	if f, ok := method.(Callable); err == nil && ok && f.Arity() == 0 {
+		call := Token{Lexeme: "toString", File: at.File, Line: at.Line, Column: at.Column, Start: at.Start, End: at.End}
+		return Stringify(i.callAt(f, nil, call))
+	}
+	return Stringify(value)
+}
+
+// Str is the str() native, which converts any value to a string the way
+// print shows it.
+type Str struct{}
+
+// Arity ...
+func (s Str) Arity() int {
+	return 1
+}
+
+// Call ...
+func (s Str) Call(i *Interpreter, args []interface{}) interface{} {
+	return i.stringify(args[0], Span{})
+}
@@ -0,0 +1,82 @@
+package lox
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// TestStringify checks how values of every type are shown.
+func TestStringify(t *testing.T) {
+	fn := NewFunction(FunctionStmt{Name: Token{Lexeme: "f"}}, nil)
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "nil"},
+		{true, "true"},
+		{3.0, "3"},
+		{-0.5, "-0.5"},
+		{1e300, "1e+300"},
+		{math.Inf(-1), "-inf"},
+		{"text", "text"},
+		{fn, "<fn f>"},
+		{&Clock{}, "<native fn>"},
+		{NewList([]interface{}{1.0, nil, "a"}), "[1, nil, a]"},
+		{money{250}, "$2.50"},
+	}
+	for _, test := range tests {
+		if got := Stringify(test.value); got != test.expected {
+			t.Errorf("Stringify(%#v) = %q, expected %q", test.value, got, test.expected)
+		}
+	}
+}
+
+// TestToString checks print, concatenation and str() in every engine,
+// including objects with a toString method.
+func TestToString(t *testing.T) {
+	stmts, err := Parse(`
+fun describe() { return "a point"; }
+point.toString = describe;
+print point;
+print "at " + point + ": " + 1.5;
+print str(nil) + str(2) + str(true);
+print str(point);
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, run := range engines(stmts) {
+		stdout := bytes.Buffer{}
+		i := NewInterpreter(WithStdout(&stdout))
+		i.Define("point", map[string]interface{}{"x": 1})
+		if ds := run(i); len(ds) > 0 {
+			t.Fatalf("%s: %v", name, ds)
+		}
+		expected := "a point\nat a point: 1.5\nnil2true\na point\n"
+		if got := stdout.String(); got != expected {
+			t.Errorf("%s: got %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+// TestRecursiveToString checks that a toString that converts its own
+// object raises a stack overflow instead of crashing the host.
+func TestRecursiveToString(t *testing.T) {
+	stmts, err := Parse(`
+fun describe() { return "p" + point; }
+point.toString = describe;
+print point;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, run := range engines(stmts) {
+		i := NewInterpreter(WithStdout(&bytes.Buffer{}), WithStderr(&bytes.Buffer{}), WithMaxRecursion(100))
+		i.Define("point", map[string]interface{}{"x": 1})
+		ds := run(i)
+		if len(ds) != 1 || ds[0].Message != "Stack overflow." {
+			t.Errorf("%s: got %v, expected a stack overflow", name, ds)
+		}
+	}
+}
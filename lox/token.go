@@ -10,6 +10,12 @@ type Token struct {
 	Lexeme  string
 	Literal interface{}
 	Line    int
+	// Column is the byte column of the start of the token, from 1. Start
+	// and End are its byte offsets in the source of File.
+	Column int
+	Start  int
+	End    int
+	File   string
 }
 
 // NewToken ...
@@ -22,6 +28,11 @@ func NewToken(tokenType TokenType, lexeme string, literal interface{}, line int)
 	return t
 }
 
+// Span ...
+func (t Token) Span() Span {
+	return Span{File: t.File, Line: t.Line, Column: t.Column, Start: t.Start, End: t.End}
+}
+
 // String ...
 func (t Token) String() string {
 	return fmt.Sprintf("TokenType:%s Lexeme:%s Literal:%v", t.Type, t.Lexeme, t.Literal)
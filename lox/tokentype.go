@@ -30,6 +30,8 @@ const (
 	TokenTypeGreaterEqual
 	TokenTypeLess
 	TokenTypeLessEqual
+	TokenTypeDotDot
+	TokenTypeDotDotEqual
 
 	//Literals
 
@@ -55,5 +57,12 @@ const (
 	TokenTypeVar
 	TokenTypeWhile
 	TokenTypeTrue
+	TokenTypeYield
+	TokenTypeSpawn
+	TokenTypeSelect
+	TokenTypeCase
+	TokenTypeDefault
+	TokenTypeAsync
+	TokenTypeAwait
 	TokenTypeEOF
 )
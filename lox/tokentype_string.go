@@ -27,31 +27,40 @@ func _() {
 	_ = x[TokenTypeGreaterEqual-17]
 	_ = x[TokenTypeLess-18]
 	_ = x[TokenTypeLessEqual-19]
-	_ = x[TokenTypeIdentifier-20]
-	_ = x[TokenTypeString-21]
-	_ = x[TokenTypeNumber-22]
-	_ = x[TokenTypeAnd-23]
-	_ = x[TokenTypeClass-24]
-	_ = x[TokenTypeElse-25]
-	_ = x[TokenTypeFalse-26]
-	_ = x[TokenTypeFun-27]
-	_ = x[TokenTypeFor-28]
-	_ = x[TokenTypeIf-29]
-	_ = x[TokenTypeNil-30]
-	_ = x[TokenTypeOr-31]
-	_ = x[TokenTypePrint-32]
-	_ = x[TokenTypeReturn-33]
-	_ = x[TokenTypeSuper-34]
-	_ = x[TokenTypeThis-35]
-	_ = x[TokenTypeVar-36]
-	_ = x[TokenTypeWhile-37]
-	_ = x[TokenTypeTrue-38]
-	_ = x[TokenTypeEOF-39]
+	_ = x[TokenTypeDotDot-20]
+	_ = x[TokenTypeDotDotEqual-21]
+	_ = x[TokenTypeIdentifier-22]
+	_ = x[TokenTypeString-23]
+	_ = x[TokenTypeNumber-24]
+	_ = x[TokenTypeAnd-25]
+	_ = x[TokenTypeClass-26]
+	_ = x[TokenTypeElse-27]
+	_ = x[TokenTypeFalse-28]
+	_ = x[TokenTypeFun-29]
+	_ = x[TokenTypeFor-30]
+	_ = x[TokenTypeIf-31]
+	_ = x[TokenTypeNil-32]
+	_ = x[TokenTypeOr-33]
+	_ = x[TokenTypePrint-34]
+	_ = x[TokenTypeReturn-35]
+	_ = x[TokenTypeSuper-36]
+	_ = x[TokenTypeThis-37]
+	_ = x[TokenTypeVar-38]
+	_ = x[TokenTypeWhile-39]
+	_ = x[TokenTypeTrue-40]
+	_ = x[TokenTypeYield-41]
+	_ = x[TokenTypeSpawn-42]
+	_ = x[TokenTypeSelect-43]
+	_ = x[TokenTypeCase-44]
+	_ = x[TokenTypeDefault-45]
+	_ = x[TokenTypeAsync-46]
+	_ = x[TokenTypeAwait-47]
+	_ = x[TokenTypeEOF-48]
 }
 
-const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGNUMBERANDCLASSELSEFALSEFUNFORIFNILORPRINTRETURNSUPERTHISVARWHILETRUEEOF"
+const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALDOT_DOTDOT_DOT_EQUALIDENTIFIERSTRINGNUMBERANDCLASSELSEFALSEFUNFORIFNILORPRINTRETURNSUPERTHISVARWHILETRUEYIELDSPAWNSELECTCASEDEFAULTASYNCAWAITEOF"
 
-var _TokenType_index = [...]uint8{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 81, 91, 96, 107, 114, 127, 131, 141, 151, 157, 163, 166, 171, 175, 180, 183, 186, 188, 191, 193, 198, 204, 209, 213, 216, 221, 225, 228}
+var _TokenType_index = [...]uint16{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 81, 91, 96, 107, 114, 127, 131, 141, 148, 161, 171, 177, 183, 186, 191, 195, 200, 203, 206, 208, 211, 213, 218, 224, 229, 233, 236, 241, 245, 250, 255, 261, 265, 272, 277, 282, 285}
 
 func (i TokenType) String() string {
 	i -= 1
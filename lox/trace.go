@@ -0,0 +1,131 @@
+package lox
+
+import "fmt"
+
+// Frame is an entry of the traceback of a runtime error: a function and
+// the position execution had reached in it. The outermost frame is the
+// script itself.
+type Frame struct {
+	Function string
+	Span     Span
+}
+
+// String formats the frame as "[line N] in f()", or "[file:N] in f()" when
+// the span records a file.
+func (f Frame) String() string {
+	where := fmt.Sprintf("line %d", f.Span.Line)
+	if f.Span.File != "" {
+		where = fmt.Sprintf("%s:%d", f.Span.File, f.Span.Line)
+	}
+	if f.Function == "script" {
+		return fmt.Sprintf("[%s] in script", where)
+	}
+	return fmt.Sprintf("[%s] in %s()", where, f.Function)
+}
+
+// maxRepeatedFrames is how many identical frames in a row a traceback
+// shows before summing up the rest, as after a stack overflow.
+const maxRepeatedFrames = 3
+
+// tracebackLines formats trace a frame per line.
+func tracebackLines(trace []Frame) []string {
+	lines := make([]string, 0, len(trace))
+	for idx := 0; idx < len(trace); {
+		line := trace[idx].String()
+		run := 1
+		for idx+run < len(trace) && trace[idx+run].String() == line {
+			run++
+		}
+		for n := 0; n < run && n < maxRepeatedFrames; n++ {
+			lines = append(lines, line)
+		}
+		if run > maxRepeatedFrames {
+			lines = append(lines, fmt.Sprintf("[Previous frame repeated %d more times]", run-maxRepeatedFrames))
+		}
+		idx += run
+	}
+	return lines
+}
+
+// traceback is trace as text, a frame per line.
+func traceback(trace []Frame) string {
+	s := ""
+	for _, line := range tracebackLines(trace) {
+		s += line + "\n"
+	}
+	return s
+}
+
+// frame is a call in progress in the tree-walking interpreter. Calls of
+// natives have frames without a function name, which tracebacks leave out.
+type frame struct {
+	function string
+	// call is the ')' of the call expression, in the caller.
+	call   Token
+	caller *frame
+}
+
+// trace returns the traceback of the calls in progress, innermost first,
+// for execution having reached span in the innermost one.
+func (i *Interpreter) trace(span Span) []Frame {
+	trace := make([]Frame, 0)
+	for f := i.frames; f != nil; f = f.caller {
+		if span.File == "" {
+			span.File = f.call.File
+		}
+		if f.function != "" {
+			trace = append(trace, Frame{Function: f.function, Span: span})
+		}
+		span = f.call.Span()
+	}
+	return append(trace, Frame{Function: "script", Span: span})
+}
+
+// traced records the calls in progress on r, if it is a runtime error
+// raised inside a function that has no traceback yet.
+func (i *Interpreter) traced(r interface{}) {
+	re, ok := r.(*RuntimeError)
+	if !ok || re.Trace != nil || i.frames == nil {
+		return
+	}
+	span := Span{Line: re.Line}
+	if re.Token != nil {
+		span = re.Token.Span()
+	}
+	// Only natives were called at the top level.
+	if trace := i.trace(span); len(trace) > 1 {
+		re.Trace = trace
+	}
+}
+
+// tracer is an engine that keeps its own frames: the VM or a frame of the
+// closure compiler. While it calls a native, it is the tracer of the
+// interpreter, and stacktrace() returns its calls in progress, innermost
+// first, for the native having been called.
+type tracer interface {
+	stacktrace() []Frame
+}
+
+// StackTrace is the stacktrace() native. It returns the calls in progress
+// as a list of strings, innermost first.
+type StackTrace struct{}
+
+// Arity ...
+func (s StackTrace) Arity() int {
+	return 0
+}
+
+// Call ...
+func (s StackTrace) Call(i *Interpreter, args []interface{}) interface{} {
+	elements := make([]interface{}, 0)
+	var trace []Frame
+	if i.tracer != nil {
+		trace = i.tracer.stacktrace()
+	} else if i.frames != nil {
+		trace = i.trace(Span{})
+	}
+	for _, f := range trace {
+		elements = append(elements, f.String())
+	}
+	return NewList(elements)
+}
@@ -0,0 +1,71 @@
+package lox
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+const traceSource = `fun inner(x) {
+  return x - "a";
+}
+fun outer() {
+  return inner(1);
+}
+outer();
+`
+
+// TestTraceback checks the traceback of a runtime error raised in nested
+// calls, both returned and printed.
+func TestTraceback(t *testing.T) {
+	stmts, err := Parse(traceSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[line 2] Error: Operands must be numbers.\n" +
+		"[line 2] in inner()\n" +
+		"[line 5] in outer()\n" +
+		"[line 7] in script\n"
+	for name, run := range engines(stmts) {
+		stderr := bytes.Buffer{}
+		ds := run(NewInterpreter(WithStderr(&stderr)))
+		if got := ds.String(); got != expected {
+			t.Errorf("%s: got %q, expected %q", name, got, expected)
+		}
+		if len(ds) > 0 && (ds[0].Span.Column != 12 || ds[0].Trace[0].Span.Column != 12 || ds[0].Trace[1].Span.Column != 17) {
+			t.Errorf("%s: got %v, expected the error at the '-' and the call at its ')'", name, ds[0].Trace)
+		}
+		if got := stderr.String(); got != expected {
+			t.Errorf("%s: printed %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+// TestStackTrace checks that stacktrace() lists the calls in progress,
+// innermost first, in every engine.
+func TestStackTrace(t *testing.T) {
+	stmts, err := ParseFile("trace.lox", `
+fun f() { return stacktrace(); }
+fun g() {
+  return f();
+}
+var trace = g();
+var top = stacktrace();
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{"[trace.lox:2] in f()", "[trace.lox:4] in g()", "[trace.lox:6] in script"}
+	for name, run := range engines(stmts) {
+		i := NewInterpreter()
+		if ds := run(i); len(ds) > 0 {
+			t.Fatalf("%s: %v", name, ds)
+		}
+		if got := i.GlobalEnv.Get("trace").(*List).Elements; !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: got %v, expected %v", name, got, expected)
+		}
+		if got := i.GlobalEnv.Get("top").(*List).Elements; !reflect.DeepEqual(got, []interface{}{"[trace.lox:7] in script"}) {
+			t.Errorf("%s: got %v at the top level", name, got)
+		}
+	}
+}
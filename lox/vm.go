@@ -0,0 +1,458 @@
+package lox
+
+import (
+	"fmt"
+)
+
+// VM executes compiled bytecode on a value stack. It shares the globals,
+// natives, tasks and event loop of the Interpreter it is created for, so
+// both engines can run the same programs.
+type VM struct {
+	interp       *Interpreter
+	stack        []interface{}
+	frames       []callFrame
+	openUpvalues []*upvalue
+}
+
+type callFrame struct {
+	closure *Closure
+	ip      int
+	base    int
+}
+
+// Closure is a compiled function bound to the variables it captures.
+type Closure struct {
+	Function *CompiledFunction
+	upvalues []*upvalue
+	vm       *VM
+}
+
+// upvalue is a captured variable. It refers to a stack slot while the
+// variable is in scope and holds the value itself once the slot is gone.
+type upvalue struct {
+	slot   int
+	open   bool
+	closed interface{}
+}
+
+// NewVM ...
+func NewVM(interp *Interpreter) *VM {
+	return &VM{interp: interp}
+}
+
+// Interpret compiles statements and runs them, reporting compile and
+// runtime errors like Interpreter.Interpret does.
+func (vm *VM) Interpret(statements []Stmt) Diagnostics {
+	r := vm.interp.reporter
+	r.start(false)
+	fn, errs := Compile(statements)
+	for _, e := range errs {
+		r.report(e)
+	}
+	if len(errs) == 0 {
+		vm.execute(fn)
+	}
+	return r.stop().Diagnostics()
+}
+
+// Run executes a compiled script, reporting runtime errors like
+// Interpreter.Interpret does.
+func (vm *VM) Run(fn *CompiledFunction) Diagnostics {
+	vm.interp.reporter.start(false)
+	vm.execute(fn)
+	return vm.interp.reporter.stop().Diagnostics()
+}
+
+func (vm *VM) execute(fn *CompiledFunction) {
+	i := vm.interp
+	i.begin(i.limits.ctx)
+	i.tasks.enter(i.task)
+	defer i.tasks.leave(i.task)
+	defer func() {
+		if r := recover(); r != nil {
+			if re, ok := r.(*RuntimeError); ok {
+				vm.traced(re)
+			}
+			vm.reset()
+			switch e := r.(type) {
+			case *RuntimeError:
+				i.reporter.report(e)
+			case *DeadlockError:
+				i.reporter.report(e)
+			case *LimitError:
+				i.reporter.report(e)
+			default:
+				panic(r)
+			}
+		}
+	}()
+	vm.reset()
+	closure := &Closure{Function: fn, vm: vm}
+	vm.push(closure)
+	vm.call(closure, 0)
+	vm.run(0)
+	i.loop.Run()
+}
+
+// trace returns the traceback of the calls in progress, innermost first,
+// for execution having reached span in the innermost one. The other frames
+// are at the call they are making.
+func (vm *VM) trace(span Span) []Frame {
+	trace := make([]Frame, 0, len(vm.frames))
+	for idx := len(vm.frames) - 1; idx >= 0; idx-- {
+		f := vm.frames[idx]
+		if idx < len(vm.frames)-1 && f.ip > 0 {
+			span = f.closure.Function.Chunk.Spans[f.ip-1]
+		}
+		name := f.closure.Function.Name
+		if name == "" {
+			name = "script"
+		}
+		trace = append(trace, Frame{Function: name, Span: span})
+	}
+	return trace
+}
+
+// stacktrace is the traceback while the VM calls a native, which it does
+// once the ip of the calling frame is saved.
+func (vm *VM) stacktrace() []Frame {
+	f := vm.frames[len(vm.frames)-1]
+	return vm.trace(f.closure.Function.Chunk.Spans[f.ip-1])
+}
+
+// traced records the calls in progress on re when it was raised inside a
+// function, as the tree-walking interpreter does.
+func (vm *VM) traced(re *RuntimeError) {
+	if re.Trace != nil || len(vm.frames) < 2 {
+		return
+	}
+	span := Span{Line: re.Line}
+	if re.Token != nil {
+		span = re.Token.Span()
+	}
+	re.Trace = vm.trace(span)
+}
+
+func (vm *VM) reset() {
+	vm.stack = vm.stack[:0]
+	vm.frames = vm.frames[:0]
+	vm.openUpvalues = nil
+}
+
+// Call runs the closure to completion, so natives and the event loop can
+// call back into compiled code.
+func (c *Closure) Call(i *Interpreter, args []interface{}) interface{} {
+	vm := c.vm
+	depth := len(vm.frames)
+	top := len(vm.stack)
+	defer func() {
+		if r := recover(); r != nil {
+			vm.closeUpvalues(top)
+			vm.frames = vm.frames[:depth]
+			vm.stack = vm.stack[:top]
+			panic(r)
+		}
+	}()
+	vm.interp.enterCall(len(vm.frames)+1, 0)
+	vm.push(c)
+	for _, arg := range args {
+		vm.push(arg)
+	}
+	vm.call(c, len(args))
+	return vm.run(depth)
+}
+
+// Arity ...
+func (c *Closure) Arity() int {
+	return c.Function.Arity
+}
+
+// String ...
+func (c *Closure) String() string {
+	return c.Function.String()
+}
+
+func (vm *VM) push(value interface{}) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *VM) pop() interface{} {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+func (vm *VM) peek(distance int) interface{} {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+func (vm *VM) call(closure *Closure, argCount int) {
+	vm.frames = append(vm.frames, callFrame{closure: closure, base: len(vm.stack) - argCount - 1})
+}
+
+// callValue calls the callee sitting below argCount arguments, for the
+// call whose closing parenthesis is at span. Compiled closures get a new
+// frame; any other Callable is called directly and its result replaces the
+// callee and arguments.
+func (vm *VM) callValue(argCount int, span Span) {
+	paren := tokenAt(Token{Type: TokenTypeRightParen, Lexeme: ")"}, span)
+	callee := vm.peek(argCount)
+	if closure, ok := callee.(*Closure); ok && closure.vm == vm {
+		if argCount != closure.Function.Arity {
+			panic(runtimeErr(paren, fmt.Sprintf("Expected %d arguments but got %d.", closure.Function.Arity, argCount)))
+		}
+		vm.interp.enterCall(len(vm.frames), span.Line)
+		vm.call(closure, argCount)
+		return
+	}
+	f, ok := callee.(Callable)
+	if !ok {
+		panic(runtimeErr(paren, "Can only call functions and classes."))
+	}
+	if !arityMatches(f, argCount) {
+		panic(runtimeErr(paren, fmt.Sprintf("Expected %d arguments but got %d.", f.Arity(), argCount)))
+	}
+	vm.interp.enterCall(len(vm.frames), span.Line)
+	args := make([]interface{}, argCount)
+	copy(args, vm.stack[len(vm.stack)-argCount:])
+	vm.replaceCall(argCount, callNative(vm.interp, f, args, paren, vm))
+}
+
+func (vm *VM) replaceCall(argCount int, result interface{}) {
+	vm.stack = vm.stack[:len(vm.stack)-argCount-1]
+	vm.push(result)
+}
+
+func (vm *VM) captureUpvalue(slot int) *upvalue {
+	for _, uv := range vm.openUpvalues {
+		if uv.slot == slot {
+			return uv
+		}
+	}
+	uv := &upvalue{slot: slot, open: true}
+	vm.openUpvalues = append(vm.openUpvalues, uv)
+	return uv
+}
+
+// closeUpvalues moves every captured variable living at or above slot off
+// the stack.
+func (vm *VM) closeUpvalues(slot int) {
+	open := vm.openUpvalues[:0]
+	for _, uv := range vm.openUpvalues {
+		if uv.slot >= slot {
+			uv.closed = vm.stack[uv.slot]
+			uv.open = false
+		} else {
+			open = append(open, uv)
+		}
+	}
+	vm.openUpvalues = open
+}
+
+func (vm *VM) getUpvalue(uv *upvalue) interface{} {
+	if uv.open {
+		return vm.stack[uv.slot]
+	}
+	return uv.closed
+}
+
+func (vm *VM) setUpvalue(uv *upvalue, value interface{}) {
+	if uv.open {
+		vm.stack[uv.slot] = value
+	} else {
+		uv.closed = value
+	}
+}
+
+var opOperators = map[OpCode]Token{
+	OpEqual:          {Type: TokenTypeEqualEqual, Lexeme: "=="},
+	OpNotEqual:       {Type: TokenTypeBangEqual, Lexeme: "!="},
+	OpGreater:        {Type: TokenTypeGreater, Lexeme: ">"},
+	OpGreaterEqual:   {Type: TokenTypeGreaterEqual, Lexeme: ">="},
+	OpLess:           {Type: TokenTypeLess, Lexeme: "<"},
+	OpLessEqual:      {Type: TokenTypeLessEqual, Lexeme: "<="},
+	OpAdd:            {Type: TokenTypePlus, Lexeme: "+"},
+	OpSubtract:       {Type: TokenTypeMinus, Lexeme: "-"},
+	OpMultiply:       {Type: TokenTypeStar, Lexeme: "*"},
+	OpDivide:         {Type: TokenTypeSlash, Lexeme: "/"},
+	OpRange:          {Type: TokenTypeDotDot, Lexeme: ".."},
+	OpRangeInclusive: {Type: TokenTypeDotDotEqual, Lexeme: "..="},
+	OpNot:            {Type: TokenTypeBang, Lexeme: "!"},
+	OpNegate:         {Type: TokenTypeMinus, Lexeme: "-"},
+}
+
+// tokenAt places t at span, to rebuild the tokens an instruction was
+// compiled from for the errors it raises.
+func tokenAt(t Token, span Span) Token {
+	t.File, t.Line, t.Column, t.Start, t.End = span.File, span.Line, span.Column, span.Start, span.End
+	return t
+}
+
+// operator rebuilds the token of the operator compiled to op, for the
+// operator helpers shared with the Interpreter.
+func operator(op OpCode, span Span) Token {
+	return tokenAt(opOperators[op], span)
+}
+
+// identifier rebuilds the token of an identifier, for the errors about
+// undefined variables and properties.
+func identifier(lexeme string, span Span) Token {
+	return tokenAt(Token{Type: TokenTypeIdentifier, Lexeme: lexeme}, span)
+}
+
+// run executes instructions until the frame count drops back to depth and
+// returns the value returned by the last frame.
+func (vm *VM) run(depth int) interface{} {
+	i := vm.interp
+	frame := &vm.frames[len(vm.frames)-1]
+	chunk := &frame.closure.Function.Chunk
+	code := chunk.Code
+	ip := frame.ip
+
+	readShort := func() int {
+		ip += 2
+		return int(code[ip-2])<<8 | int(code[ip-1])
+	}
+	// reload switches to the frame on top of the stack after a call or
+	// return, or after anything that may have called back into compiled
+	// code and so moved the frames; the current ip must be saved first.
+	reload := func() {
+		frame = &vm.frames[len(vm.frames)-1]
+		chunk = &frame.closure.Function.Chunk
+		code = chunk.Code
+		ip = frame.ip
+	}
+
+	// Instructions are only counted when there is something to check the
+	// count against.
+	counting := i.limits.counting
+	for {
+		if counting {
+			i.limits.step(chunk.Lines[ip])
+		}
+		op := OpCode(code[ip])
+		ip++
+		switch op {
+		case OpConstant:
+			vm.push(chunk.Constants[readShort()])
+		case OpNil:
+			vm.push(nil)
+		case OpTrue:
+			vm.push(true)
+		case OpFalse:
+			vm.push(false)
+		case OpPop:
+			vm.pop()
+		case OpGetLocal:
+			vm.push(vm.stack[frame.base+int(code[ip])])
+			ip++
+		case OpSetLocal:
+			vm.stack[frame.base+int(code[ip])] = vm.peek(0)
+			ip++
+		case OpGetGlobal:
+			vm.push(i.getGlobal(identifier(chunk.Constants[readShort()].(string), chunk.Spans[ip-1])))
+		case OpDefineGlobal:
+			i.GlobalEnv.Define(chunk.Constants[readShort()].(string), vm.pop())
+		case OpSetGlobal:
+			i.assignGlobal(identifier(chunk.Constants[readShort()].(string), chunk.Spans[ip-1]), vm.peek(0))
+		case OpGetUpvalue:
+			vm.push(vm.getUpvalue(frame.closure.upvalues[code[ip]]))
+			ip++
+		case OpSetUpvalue:
+			vm.setUpvalue(frame.closure.upvalues[code[ip]], vm.peek(0))
+			ip++
+		case OpGetProperty:
+			vm.push(getProperty(vm.pop(), identifier(chunk.Constants[readShort()].(string), chunk.Spans[ip-1])))
+		case OpSetProperty:
+			property := identifier(chunk.Constants[readShort()].(string), chunk.Spans[ip-1])
+			value := vm.pop()
+			setProperty(vm.pop(), property, value)
+			vm.push(value)
+		case OpAdd, OpSubtract, OpMultiply, OpDivide, OpGreater, OpGreaterEqual, OpLess, OpLessEqual:
+			right := vm.pop()
+			left := vm.pop()
+			a, okLeft := left.(float64)
+			b, okRight := right.(float64)
+			if !okLeft || !okRight {
+				// Concatenation may call toString methods.
+				frame.ip = ip
+				vm.push(i.binaryOp(operator(op, chunk.Spans[ip-1]), left, right))
+				reload()
+				continue
+			}
+			switch op {
+			case OpAdd:
+				vm.push(a + b)
+			case OpSubtract:
+				vm.push(a - b)
+			case OpMultiply:
+				vm.push(a * b)
+			case OpDivide:
+				vm.push(a / b)
+			case OpGreater:
+				vm.push(a > b)
+			case OpGreaterEqual:
+				vm.push(a >= b)
+			case OpLess:
+				vm.push(a < b)
+			case OpLessEqual:
+				vm.push(a <= b)
+			}
+		case OpEqual, OpNotEqual, OpRange, OpRangeInclusive:
+			right := vm.pop()
+			left := vm.pop()
+			vm.push(i.binaryOp(operator(op, chunk.Spans[ip-1]), left, right))
+		case OpNot, OpNegate:
+			vm.push(i.unaryOp(operator(op, chunk.Spans[ip-1]), vm.pop()))
+		case OpPrint:
+			frame.ip = ip
+			fmt.Fprintln(i.stdout, i.stringify(vm.pop(), chunk.Spans[ip-1]))
+			reload()
+		case OpJump:
+			offset := readShort()
+			ip += offset
+		case OpJumpIfFalse:
+			offset := readShort()
+			if !i.isTruthy(vm.peek(0)) {
+				ip += offset
+			}
+		case OpLoop:
+			offset := readShort()
+			ip -= offset
+		case OpCall:
+			argCount := int(code[ip])
+			ip++
+			frame.ip = ip
+			vm.callValue(argCount, chunk.Spans[ip-1])
+			reload()
+		case OpClosure:
+			fn := chunk.Constants[readShort()].(*CompiledFunction)
+			closure := &Closure{Function: fn, upvalues: make([]*upvalue, fn.UpvalueCount), vm: vm}
+			for idx := range closure.upvalues {
+				isLocal, index := code[ip], int(code[ip+1])
+				ip += 2
+				if isLocal == 1 {
+					closure.upvalues[idx] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.upvalues[idx] = frame.closure.upvalues[index]
+				}
+			}
+			vm.push(closure)
+		case OpCloseUpvalue:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			vm.stack = vm.stack[:frame.base]
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) == depth {
+				return result
+			}
+			vm.push(result)
+			reload()
+		default:
+			panic(&RuntimeError{Line: chunk.Lines[ip-1], Msg: fmt.Sprintf("Unknown opcode %d.", op)})
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package lox
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var engineTests = []struct {
+	name   string
+	source string
+	result string
+}{
+	{"fib", `
+fun fib(n) { if (n < 2) return n; return fib(n - 1) + fib(n - 2); }
+var result = fib(15);
+`, "610"},
+	{"closures", `
+fun counter() {
+  var count = 0;
+  fun increment() { count = count + 1; return count; }
+  return increment;
+}
+var c = counter();
+c();
+var result = c();
+`, "2"},
+	{"shadowing", `
+var result = "global";
+{
+  var a = "outer";
+  { var a = a + " inner"; result = a; }
+}
+`, "outer inner"},
+	{"loops", `
+var result = "";
+for (var i = 0; i < 3; i = i + 1) {
+  var j = 0;
+  while (j < i) { result = result + "x"; j = j + 1; }
+  result = result + "|";
+}
+`, "|x|xx|"},
+	{"logical", `
+var a = nil or "d";
+var b = false and 1;
+var result = a;
+if (b) result = "b"; else result = result + "!";
+`, "d!"},
+	{"natives", `
+var result = list(step(1..=10, 3));
+`, "[1, 4, 7, 10]"},
+	// The programs of the tree-walking interpreter's own tests.
+	{"scopes", scopesProgram, "globalglobal block inner"},
+	{"generator", generatorProgram + "var result = taken;", "[0, 1, 2, 3]"},
+	{"channels", channelsProgram + "var result = total;", "14"},
+	{"select", selectProgram + "var result = got + fallback;", "bdefault"},
+	{"async", asyncProgram, "slow fast "},
+	{"await", `
+fun later(value) { await sleep(1); return value; }
+var result = later("done");
+`, "done"},
+}
+
+// unsupported lists, per engine, the features of the tree-walking
+// interpreter that are out of scope for the engine, which rejects them with
+// a compile error. Engine tests using one of them are skipped for the
+// engine; every feature listed must be used by a test, so the list goes
+// stale neither way. yield is missing as it can only appear in generator
+// functions.
+var unsupported = map[string][]string{
+	"vm":      {"Generator function", "Async function", "await", "spawn", "select"},
+	"closure": {"Generator function", "Async function", "await", "spawn", "select"},
+}
+
+// unsupportedFeatures returns the features listed for engine that ds
+// rejects the program for, or nil if ds has other errors too.
+func unsupportedFeatures(engine string, ds Diagnostics) []string {
+	var features []string
+	seen := make(map[string]bool)
+	for _, d := range ds {
+		feature := ""
+		for _, f := range unsupported[engine] {
+			if d.Phase == PhaseCompile && strings.HasPrefix(d.Message, f+" is not supported by ") {
+				feature = f
+			}
+		}
+		if feature == "" {
+			return nil
+		}
+		if !seen[feature] {
+			seen[feature] = true
+			features = append(features, feature)
+		}
+	}
+	return features
+}
+
+// engines returns a function per engine that runs stmts with an
+// interpreter. Compile errors are returned as diagnostics by every engine.
+func engines(stmts []Stmt) map[string]func(i *Interpreter) Diagnostics {
+	return map[string]func(i *Interpreter) Diagnostics{
+		"tree": func(i *Interpreter) Diagnostics { return i.Interpret(stmts) },
+		"vm":   func(i *Interpreter) Diagnostics { return NewVM(i).Interpret(stmts) },
+		"closure": func(i *Interpreter) Diagnostics {
+			program, errs := CompileClosures(stmts)
+			if len(errs) > 0 {
+				return ErrorList(errs).Diagnostics()
+			}
+			return program.Run(i)
+		},
+	}
+}
+
+// TestEngines runs every engine test in every engine, skipping the engines
+// that do not support a feature the test uses.
+func TestEngines(t *testing.T) {
+	skipped := make(map[string]map[string]bool)
+	for _, test := range engineTests {
+		stmts, err := Parse(test.source)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		for name, run := range engines(stmts) {
+			i := NewInterpreter(WithStdout(&bytes.Buffer{}), WithStderr(&bytes.Buffer{}))
+			ds := run(i)
+			if features := unsupportedFeatures(name, ds); len(features) > 0 {
+				t.Logf("%s: skipped in %s, which does not support %s", test.name, name, strings.Join(features, ", "))
+				for _, feature := range features {
+					if skipped[name] == nil {
+						skipped[name] = make(map[string]bool)
+					}
+					skipped[name][feature] = true
+				}
+				continue
+			}
+			if len(ds) > 0 {
+				t.Errorf("%s: %s got %v", test.name, name, ds)
+				continue
+			}
+			if got := Stringify(i.GlobalEnv.Get("result")); got != test.result {
+				t.Errorf("%s: %s got %s, expected %s", test.name, name, got, test.result)
+			}
+		}
+	}
+	for name, features := range unsupported {
+		for _, feature := range features {
+			if !skipped[name][feature] {
+				t.Errorf("%s is listed as unsupported by %s, but no engine test was skipped for it", feature, name)
+			}
+		}
+	}
+}
+
+// TestDisassemble ...
+func TestDisassemble(t *testing.T) {
+	fn, errs := Compile(mustParse("fun f(a) { return a + 1; }\nprint f(2);"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	var out bytes.Buffer
+	Disassemble(&out, fn)
+	for _, want := range []string{"== script ==", "OP_CLOSURE", "== f ==", "OP_GET_LOCAL", "OP_ADD", "OP_RETURN"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("disassembly is missing %s:\n%s", want, out.String())
+		}
+	}
+}
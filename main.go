@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -14,53 +15,73 @@ import (
 
 // Lox struct for the lox class
 type Lox struct {
-	HadError        bool
-	HadRuntimeError bool
-	Interpreter     *lox.Interpreter
+	// ExitCode is the exit status of the last run.
+	ExitCode    int
+	Interpreter *lox.Interpreter
+	VM          *lox.VM
+	Engine      string
+	Renderer    *lox.Renderer
 }
 
 // NewLox Constructor for lox
-func NewLox() *Lox {
+func NewLox(opts ...lox.Option) *Lox {
 	l := new(Lox)
-	l.HadError = false
-	l.HadRuntimeError = false
-	l.Interpreter = lox.NewInterpreter()
+	l.Renderer = &lox.Renderer{}
+	l.Interpreter = lox.NewInterpreter(append(opts, lox.WithRenderer(l.Renderer))...)
+	l.VM = lox.NewVM(l.Interpreter)
 	return l
 }
 
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: golox [-engine tree|vm|closure] [-dialect extended|strict] [-no-color] [-plain] [script]")
+	fmt.Fprintln(os.Stderr, "       golox [-dialect extended|strict] [-no-color] [-plain] dis script")
+}
+
 func main() {
-	if len(os.Args) > 2 {
-		fmt.Println("Usage: go[script]")
+	engine := flag.String("engine", "tree", "execution engine, tree, vm or closure")
+	noColor := flag.Bool("no-color", false, "do not colour error messages")
+	plain := flag.Bool("plain", false, "print errors on one line each, for logs")
+	dialectName := flag.String("dialect", "extended", "language dialect, extended or strict (book Lox)")
+	flag.Usage = usage
+	flag.Parse()
+	args := flag.Args()
+
+	dis := len(args) > 0 && args[0] == "dis"
+	dialect, err := lox.ParseDialect(*dialectName)
+	if (dis && len(args) != 2) || (!dis && len(args) > 1) || (*engine != "tree" && *engine != "vm" && *engine != "closure") || err != nil {
+		usage()
 		os.Exit(64)
+	}
+	l := NewLox(lox.WithDialect(dialect))
+	l.Engine = *engine
+	l.Renderer.Plain = *plain
+	l.Renderer.Color = !*noColor && !*plain && readline.IsTerminal(int(os.Stderr.Fd()))
+	if dis {
+		l.disassemble(args[1])
+	} else if len(args) == 1 {
+		l.runFile(args[0])
 	} else {
-		l := NewLox()
-		if len(os.Args) == 2 {
-			l.runFile(os.Args[1])
-		} else {
-			l.runPrompt()
-		}
+		l.runPrompt()
 	}
 }
 
-func (l Lox) runFile(path string) {
+// readFile returns the source of the script at path, exiting with status
+// 66 if it cannot be read.
+func readFile(path string) string {
 	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
-		fmt.Println(err)
-		l.HadError = true
-	}
-
-	l.run(string(bytes))
-
-	if l.HadError {
-		os.Exit(65)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(66)
 	}
+	return string(bytes)
+}
 
-	if l.HadRuntimeError {
-		os.Exit(70)
-	}
+func (l *Lox) runFile(path string) {
+	l.run(path, readFile(path))
+	os.Exit(l.ExitCode)
 }
 
-func (l Lox) runPrompt() {
+func (l *Lox) runPrompt() {
 	rl, e := readline.New("> ")
 	if e != nil {
 		panic(e)
@@ -75,18 +96,68 @@ func (l Lox) runPrompt() {
 		if line == "exit" {
 			os.Exit(0)
 		}
-		l.run(line)
-		l.HadError = false
+		l.run("", line)
+	}
+}
+
+// parse parses source in the dialect of the interpreter. It renders the
+// errors and records the exit status if there are any, in which case the
+// statements are nil.
+func (l *Lox) parse(file string, source string) []lox.Stmt {
+	l.Renderer.Source = source
+	l.Renderer.File = file
+	stmts, err := l.Interpreter.Parse(file, source)
+	if err != nil {
+		l.compileErrors(err.(lox.ErrorList))
+		return nil
 	}
+	return stmts
+}
+
+// compileErrors renders errors found before the program runs and records
+// the exit status.
+func (l *Lox) compileErrors(errs lox.ErrorList) {
+	ds := errs.Diagnostics()
+	l.Renderer.RenderAll(os.Stderr, ds)
+	l.setErrors(ds)
 }
 
-func (l Lox) run(source string) {
-	s := lox.NewScanner(source)
-	tokens := s.ScanTokens()
-	p := lox.NewParser(tokens)
-	stmts := p.Parse()
-	if l.HadError {
+func (l *Lox) run(file string, source string) {
+	stmts := l.parse(file, source)
+	if stmts == nil {
 		return
 	}
-	l.Interpreter.Interpret(stmts)
+	switch l.Engine {
+	case "vm":
+		l.setErrors(l.VM.Interpret(stmts))
+	case "closure":
+		program, errs := lox.CompileClosures(stmts)
+		if len(errs) > 0 {
+			l.compileErrors(errs)
+			return
+		}
+		l.setErrors(program.Run(l.Interpreter))
+	default:
+		l.setErrors(l.Interpreter.Interpret(stmts))
+	}
+}
+
+// setErrors records the exit status for the diagnostics of a run.
+func (l *Lox) setErrors(ds lox.Diagnostics) {
+	l.ExitCode = ds.ExitCode()
+}
+
+// disassemble prints the bytecode of the script at path, which it reads and
+// parses like runFile.
+func (l *Lox) disassemble(path string) {
+	stmts := l.parse(path, readFile(path))
+	if stmts == nil {
+		os.Exit(l.ExitCode)
+	}
+	fn, errs := lox.Compile(stmts)
+	if len(errs) > 0 {
+		l.compileErrors(errs)
+		os.Exit(l.ExitCode)
+	}
+	lox.Disassemble(os.Stdout, fn)
 }